@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// problem is a deviation from the canonical form at a byte offset.
+type problem struct {
+	offset int
+	msg    string
+}
+
+// checker walks bencoded data and collects the deviations from the
+// canonical form: leading zeros, negative zero, unsorted or duplicate dict
+// keys and trailing data. It stops at the first syntax error.
+type checker struct {
+	data     []byte
+	pos      int
+	problems []problem
+}
+
+func checkCanonical(data []byte) []problem {
+	c := &checker{data: data}
+	if c.value() && c.pos < len(data) {
+		c.report(c.pos, "unexpected data after the value")
+	}
+	return c.problems
+}
+
+func (c *checker) report(offset int, format string, args ...interface{}) {
+	c.problems = append(c.problems, problem{offset, fmt.Sprintf(format, args...)})
+}
+
+// fail reports a syntax error, after which checking stops.
+func (c *checker) fail(msg string) bool {
+	c.report(c.pos, "syntax error: %s", msg)
+	return false
+}
+
+// value checks the value at the current position and reports whether it
+// is well-formed.
+func (c *checker) value() bool {
+	if c.pos >= len(c.data) {
+		return c.fail("unexpected end of data")
+	}
+	switch b := c.data[c.pos]; {
+	case b == 'i':
+		return c.integer()
+	case b == 'l':
+		c.pos++
+		for c.pos < len(c.data) && c.data[c.pos] != 'e' {
+			if !c.value() {
+				return false
+			}
+		}
+		return c.end()
+	case b == 'd':
+		return c.dict()
+	case '0' <= b && b <= '9':
+		_, ok := c.str()
+		return ok
+	}
+	return c.fail(fmt.Sprintf("unexpected token %q", c.data[c.pos]))
+}
+
+func (c *checker) end() bool {
+	if c.pos >= len(c.data) {
+		return c.fail("unexpected end of data")
+	}
+	c.pos++ // 'e'
+	return true
+}
+
+// digits returns the number at the current position, up to delim.
+func (c *checker) digits(delim byte) (string, bool) {
+	n := bytes.IndexByte(c.data[c.pos:], delim)
+	if n < 0 {
+		return "", c.fail(fmt.Sprintf("missing %q", delim))
+	}
+	s := string(c.data[c.pos : c.pos+n])
+	return s, true
+}
+
+// isInteger reports whether s is an optionally signed decimal number. The
+// number is not limited to 64 bits, bencode integers are unbounded.
+func isInteger(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *checker) integer() bool {
+	start := c.pos
+	c.pos++ // 'i'
+	s, ok := c.digits('e')
+	if !ok {
+		return false
+	}
+	if !isInteger(s) {
+		return c.fail(fmt.Sprintf("invalid integer %q", s))
+	}
+	switch {
+	case s == "-0":
+		c.report(start, "negative zero")
+	case len(s) > 1 && s[0] == '0', len(s) > 2 && s[:2] == "-0":
+		c.report(start, "integer %s has leading zeros", s)
+	case s[0] == '+':
+		c.report(start, "integer %s has a plus sign", s)
+	}
+	c.pos += len(s) + 1
+	return true
+}
+
+func (c *checker) str() ([]byte, bool) {
+	start := c.pos
+	s, ok := c.digits(':')
+	if !ok {
+		return nil, false
+	}
+	n, err := strconv.ParseUint(s, 10, 63)
+	if err != nil {
+		return nil, c.fail(fmt.Sprintf("invalid string length %q", s))
+	}
+	if len(s) > 1 && s[0] == '0' {
+		c.report(start, "string length %s has leading zeros", s)
+	}
+	c.pos += len(s) + 1
+	if n > uint64(len(c.data)-c.pos) {
+		return nil, c.fail(fmt.Sprintf("string of %d bytes exceeds the data", n))
+	}
+	b := c.data[c.pos : c.pos+int(n)]
+	c.pos += int(n)
+	return b, true
+}
+
+func (c *checker) dict() bool {
+	c.pos++ // 'd'
+	var prev []byte
+	first := true
+	for c.pos < len(c.data) && c.data[c.pos] != 'e' {
+		start := c.pos
+		if b := c.data[c.pos]; b < '0' || b > '9' {
+			return c.fail("dict key is not a string")
+		}
+		key, ok := c.str()
+		if !ok {
+			return false
+		}
+		if !first {
+			switch cmp := bytes.Compare(prev, key); {
+			case cmp == 0:
+				c.report(start, "duplicate key %q", key)
+			case cmp > 0:
+				c.report(start, "key %q is not sorted, it follows %q", key, prev)
+			}
+		}
+		prev, first = key, false
+		if !c.value() {
+			return false
+		}
+	}
+	return c.end()
+}
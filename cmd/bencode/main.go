@@ -0,0 +1,312 @@
+// Command bencode inspects and edits bencoded files.
+//
+// Usage:
+//
+//	bencode dump [-offsets] [-max n] [file]
+//	bencode validate [file]
+//	bencode json [-binary tagged|hex|base64] [-indent s] [file]
+//	bencode from-json [file]
+//	bencode get [-raw] file path
+//	bencode set [-type string|int|json|bencode] file path value
+//	bencode del file path
+//
+// A missing file or "-" reads the standard input. A path is a list of dict
+// keys and list indexes separated by slashes, such as info/files/0/length.
+// The set and del commands edit the file in place, or write the edited value
+// to the standard output when the file is "-".
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ortymid/bencode"
+)
+
+const usage = `usage: bencode <command> [flags] [args]
+
+commands:
+  dump       pretty-print a file
+  validate   check that a file is in the canonical form
+  json       convert a file to JSON
+  from-json  convert JSON to bencode
+  get        print the value at a path
+  set        set the value at a path, in place
+  del        delete the value at a path, in place`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "bencode:", err)
+		os.Exit(1)
+	}
+}
+
+type command func(args []string, stdin io.Reader, stdout io.Writer) error
+
+var commands = map[string]command{
+	"dump":      dump,
+	"validate":  validate,
+	"json":      toJSON,
+	"from-json": fromJSON,
+	"get":       get,
+	"set":       set,
+	"del":       del,
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return cmd(args[1:], stdin, stdout)
+}
+
+// parseFlags parses the flags of a command and checks the number of the
+// remaining arguments.
+func parseFlags(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if n := fs.NArg(); n < min || n > max {
+		return nil, fmt.Errorf("%s: wrong number of arguments", fs.Name())
+	}
+	return fs.Args(), nil
+}
+
+// readInput reads the named file, or stdin if the name is empty or "-".
+func readInput(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+// parse parses data which must hold exactly one value.
+func parse(data []byte) (bencode.Value, error) {
+	p := bencode.NewParser(bytes.NewReader(data))
+	v, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Parse(); err != io.EOF {
+		return nil, errors.New("unexpected data after the value")
+	}
+	return v, nil
+}
+
+func parseInput(args []string, stdin io.Reader) (bencode.Value, error) {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data)
+}
+
+func dump(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	offsets := fs.Bool("offsets", false, "show byte offsets")
+	max := fs.Int("max", 0, "truncate strings longer than `n` bytes, negative for no limit")
+	args, err := parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	v, err := parseInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	return bencode.Pretty(stdout, v, bencode.PrettyOptions{MaxString: *max, Offsets: *offsets})
+}
+
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	args, err := parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	problems := checkCanonical(data)
+	for _, p := range problems {
+		fmt.Fprintf(stdout, "%d: %s\n", p.offset, p.msg)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	return nil
+}
+
+var binaryEncodings = map[string]bencode.BinaryEncoding{
+	"tagged": bencode.BinaryTagged,
+	"hex":    bencode.BinaryHex,
+	"base64": bencode.BinaryBase64,
+}
+
+func toJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("json", flag.ContinueOnError)
+	binary := fs.String("binary", "tagged", "encoding of binary strings: tagged, hex or base64")
+	indent := fs.String("indent", "  ", "indent of nested values, empty for compact output")
+	args, err := parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	enc, ok := binaryEncodings[*binary]
+	if !ok {
+		return fmt.Errorf("json: unknown binary encoding %q", *binary)
+	}
+	v, err := parseInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	data, err := bencode.ToJSON(v, bencode.JSONOptions{Binary: enc, Indent: *indent})
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(append(data, '\n'))
+	return err
+}
+
+func fromJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("from-json", flag.ContinueOnError)
+	args, err := parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	v, err := bencode.FromJSON(data, bencode.JSONOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = v.WriteTo(stdout)
+	return err
+}
+
+func get(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	raw := fs.Bool("raw", false, "print the value as bencode")
+	args, err := parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	v, err := parseInput(args[:1], stdin)
+	if err != nil {
+		return err
+	}
+	v, err = lookup(v, splitPath(args[1]))
+	if err != nil {
+		return err
+	}
+
+	if *raw {
+		_, err = v.WriteTo(stdout)
+		return err
+	}
+	switch v := v.(type) {
+	case bencode.String:
+		_, err = fmt.Fprintf(stdout, "%s\n", string(v))
+	case bencode.Int:
+		_, err = fmt.Fprintf(stdout, "%d\n", int64(v))
+	default:
+		err = bencode.Pretty(stdout, v, bencode.PrettyOptions{})
+	}
+	return err
+}
+
+// parseArgValue converts a value given on the command line.
+func parseArgValue(s, typ string) (bencode.Value, error) {
+	switch typ {
+	case "string":
+		return bencode.String(s), nil
+	case "int":
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return bencode.Int(i), nil
+	case "json":
+		return bencode.FromJSON([]byte(s), bencode.JSONOptions{})
+	case "bencode":
+		return parse([]byte(s))
+	}
+	return nil, fmt.Errorf("set: unknown value type %q", typ)
+}
+
+func set(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	typ := fs.String("type", "string", "type of the value: string, int, json or bencode")
+	args, err := parseFlags(fs, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	val, err := parseArgValue(args[2], *typ)
+	if err != nil {
+		return err
+	}
+	return edit(args[0], stdin, stdout, func(v bencode.Value) (bencode.Value, error) {
+		return setPath(v, splitPath(args[1]), val)
+	})
+}
+
+func del(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("del", flag.ContinueOnError)
+	args, err := parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	return edit(args[0], stdin, stdout, func(v bencode.Value) (bencode.Value, error) {
+		return deletePath(v, splitPath(args[1]))
+	})
+}
+
+// edit replaces the value in the named file with the result of fn. The file
+// is replaced atomically. If the name is "-", the value is read from stdin
+// and the result is written to stdout.
+func edit(name string, stdin io.Reader, stdout io.Writer, fn func(bencode.Value) (bencode.Value, error)) error {
+	v, err := parseInput([]string{name}, stdin)
+	if err != nil {
+		return err
+	}
+	if v, err = fn(v); err != nil {
+		return err
+	}
+	if name == "-" {
+		_, err := v.WriteTo(stdout)
+		return err
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".bencode-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := v.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTorrent = "d8:announce14:http://tracker4:infod6:lengthi5e4:name4:spam12:piece lengthi16384e6:pieces20:\xf0\xf1\xf2\xf3\xf4\xf5\xf6\xf7\xf8\xf9\xfa\xfb\xfc\xfd\xfe\xff\x00\x01\x02\x03ee"
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Dump", []string{"dump", "-max", "4"}, "dict (2 keys)\n  announce: \"http\"… (14 bytes)\n  info: dict (4 keys)\n    length: 5\n    name: \"spam\"\n" +
+			"    \"piec\"… (12 bytes): 16384\n    pieces: <20 bytes> f0f1f2f3…\n"},
+		{"Validate", []string{"validate"}, ""},
+		{"JSON", []string{"json", "-binary", "hex", "-indent", ""}, `{"announce":"http://tracker","info":{"length":5,"name":"spam","piece length":16384,"pieces":"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff00010203"}}` + "\n"},
+		{"Get/String", []string{"get", "-", "announce"}, "http://tracker\n"},
+		{"Get/Int", []string{"get", "-", "/info/length"}, "5\n"},
+		{"Get/Raw", []string{"get", "-raw", "-", "info/name"}, "4:spam"},
+		{"Get/Dict", []string{"get", "-", "info"}, "dict (4 keys)\n  length: 5\n  name: \"spam\"\n  \"piece length\": 16384\n  pieces: <20 bytes> f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff00010203\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(test.args, strings.NewReader(testTorrent), &out); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("\ngot: %q \nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunJSONRoundtrip(t *testing.T) {
+	var j, got bytes.Buffer
+	if err := run([]string{"json"}, strings.NewReader(testTorrent), &j); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := run([]string{"from-json"}, &j, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if got.String() != testTorrent {
+		t.Errorf("\ngot: %q \nwant: %q", got.String(), testTorrent)
+	}
+}
+
+func TestRunEdit(t *testing.T) {
+	const fileArg = "<file>"
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Set/Replace", []string{"set", fileArg, "announce", "udp://x"}, "d8:announce7:udp://x4:infod6:lengthi1ee4:listli1eee"},
+		{"Set/New key sorted", []string{"set", "-type", "int", fileArg, "creation date", "7"}, "d8:announce1:a13:creation datei7e4:infod6:lengthi1ee4:listli1eee"},
+		{"Set/Nested", []string{"set", "-type", "int", fileArg, "info/length", "2"}, "d8:announce1:a4:infod6:lengthi2ee4:listli1eee"},
+		{"Set/Create dicts", []string{"set", fileArg, "x/y", "z"}, "d8:announce1:a4:infod6:lengthi1ee4:listli1ee1:xd1:y1:zee"},
+		{"Set/Append", []string{"set", "-type", "json", fileArg, "list/1", `{"a":[1]}`}, "d8:announce1:a4:infod6:lengthi1ee4:listli1ed1:ali1eeeee"},
+		{"Set/Bencode", []string{"set", "-type", "bencode", fileArg, "info", "de"}, "d8:announce1:a4:infode4:listli1eee"},
+		{"Del/Key", []string{"del", fileArg, "announce"}, "d4:infod6:lengthi1ee4:listli1eee"},
+		{"Del/Nested", []string{"del", fileArg, "info/length"}, "d8:announce1:a4:infode4:listli1eee"},
+		{"Del/Item", []string{"del", fileArg, "list/0"}, "d8:announce1:a4:infod6:lengthi1ee4:listlee"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "test.torrent")
+			if err := os.WriteFile(name, []byte("d8:announce1:a4:infod6:lengthi1ee4:listli1eee"), 0o644); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			args := append([]string(nil), test.args...)
+			for i := range args {
+				if args[i] == fileArg {
+					args[i] = name
+				}
+			}
+			if err := run(args, nil, nil); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			got, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("\ngot: %q \nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunEditStdin(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Set", []string{"set", "-type", "int", "-", "info/length", "2"}, "d8:announce1:a4:infod6:lengthi2eee"},
+		{"Del", []string{"del", "-", "announce"}, "d4:infod6:lengthi1eee"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(test.args, strings.NewReader("d8:announce1:a4:infod6:lengthi1eee"), &out); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("\ngot: %q \nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckCanonical(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []problem
+	}{
+		{"Canonical", "d1:ai0e1:bli-1e0:ee", nil},
+		{"Leading zeros", "li03e02:abe", []problem{{1, "integer 03 has leading zeros"}, {5, "string length 02 has leading zeros"}}},
+		{"Negative zero", "i-0e", []problem{{0, "negative zero"}}},
+		{"Unsorted keys", "d1:bi1e1:ai2ee", []problem{{7, `key "a" is not sorted, it follows "b"`}}},
+		{"Duplicate keys", "d1:ai1e1:ai2ee", []problem{{7, `duplicate key "a"`}}},
+		{"Trailing data", "i1ei2e", []problem{{3, "unexpected data after the value"}}},
+		{"Syntax error", "d1:ai1e", []problem{{7, "syntax error: unexpected end of data"}}},
+		{"Bad string", "5:ab", []problem{{2, "syntax error: string of 5 bytes exceeds the data"}}},
+		{"Non-string key", "di1ei2ee", []problem{{1, "syntax error: dict key is not a string"}}},
+		{"Big integer", "i123456789012345678901234567890e", nil},
+		{"Big integer/Leading zeros", "i-0123456789012345678901234567890e", []problem{{0, "integer -0123456789012345678901234567890 has leading zeros"}}},
+		{"Plus sign", "i+5e", []problem{{0, "integer +5 has a plus sign"}}},
+		{"Empty integer", "ie", []problem{{1, `syntax error: invalid integer ""`}}},
+		{"Sign only", "i-e", []problem{{1, `syntax error: invalid integer "-"`}}},
+		{"Bad digit", "i1xe", []problem{{1, `syntax error: invalid integer "1x"`}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := checkCanonical([]byte(test.input))
+			if len(got) != len(test.want) {
+				t.Fatalf("\ngot: %v \nwant: %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("\ngot: %v \nwant: %v", got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunError(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{"No command", nil, ""},
+		{"Unknown command", []string{"spam"}, ""},
+		{"Trailing data", []string{"dump"}, "i1ei2e"},
+		{"Not canonical", []string{"validate"}, "i01e"},
+		{"Missing key", []string{"get", "-", "eggs"}, "d4:spami1ee"},
+		{"Bad index", []string{"get", "-", "1"}, "li1ee"},
+		{"Not a container", []string{"get", "-", "spam/eggs"}, "d4:spami1ee"},
+		{"Bad binary", []string{"json", "-binary", "spam"}, "i1e"},
+		{"Arguments", []string{"get", "-"}, "i1e"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(test.args, strings.NewReader(test.input), &out); err == nil {
+				t.Error("no error for", test.args)
+			}
+		})
+	}
+}
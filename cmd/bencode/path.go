@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ortymid/bencode"
+)
+
+// splitPath splits a slash separated path. The empty path and "/" refer
+// to the root value.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// index parses a list index, allowing the index one past the end if
+// appending is true.
+func index(l bencode.List, s string, appending bool) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i > len(l) || (i == len(l) && !appending) {
+		return 0, fmt.Errorf("invalid index %q for a list of %d items", s, len(l))
+	}
+	return i, nil
+}
+
+// lookup returns the value at path.
+func lookup(v bencode.Value, path []string) (bencode.Value, error) {
+	for i, elem := range path {
+		switch c := v.(type) {
+		case *bencode.Dict:
+			if v = c.Get(bencode.String(elem)); v == nil {
+				return nil, fmt.Errorf("%s: no such key", strings.Join(path[:i+1], "/"))
+			}
+		case bencode.List:
+			j, err := index(c, elem, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.Join(path[:i+1], "/"), err)
+			}
+			v = c[j]
+		default:
+			return nil, fmt.Errorf("/%s: not a list or dict", strings.Join(path[:i], "/"))
+		}
+	}
+	return v, nil
+}
+
+// setPath returns v with the value at path set to val. Missing dict keys
+// along the path are created as dicts, and a list index one past the end
+// appends to the list.
+func setPath(v bencode.Value, path []string, val bencode.Value) (bencode.Value, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	switch c := v.(type) {
+	case *bencode.Dict:
+		key := bencode.String(path[0])
+		child := c.Get(key)
+		if child == nil {
+			child = bencode.NewDict()
+		}
+		child, err := setPath(child, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		return withKey(c, key, child), nil
+	case bencode.List:
+		i, err := index(c, path[0], len(path) == 1)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(c) {
+			return append(c, val), nil
+		}
+		if c[i], err = setPath(c[i], path[1:], val); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("cannot set %s in %T", strings.Join(path, "/"), v)
+}
+
+// withKey sets the key of d to val. A new key is inserted before the first
+// greater key, so that a dict in the canonical form stays in it.
+func withKey(d *bencode.Dict, key bencode.String, val bencode.Value) *bencode.Dict {
+	out := bencode.NewDict()
+	added := false
+	for _, k := range d.Keys() {
+		if !added && k >= key {
+			out.Set(key, val)
+			added = true
+			if k == key {
+				continue
+			}
+		}
+		out.Set(k, d.Get(k))
+	}
+	if !added {
+		out.Set(key, val)
+	}
+	return out
+}
+
+// deletePath returns v with the value at path removed.
+func deletePath(v bencode.Value, path []string) (bencode.Value, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot delete the root value")
+	}
+	switch c := v.(type) {
+	case *bencode.Dict:
+		key := bencode.String(path[0])
+		child := c.Get(key)
+		if child == nil {
+			return nil, fmt.Errorf("%s: no such key", path[0])
+		}
+		if len(path) == 1 {
+			c.Delete(key)
+			return c, nil
+		}
+		child, err := deletePath(child, path[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s/%w", path[0], err)
+		}
+		return withKey(c, key, child), nil
+	case bencode.List:
+		i, err := index(c, path[0], false)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 1 {
+			return append(c[:i], c[i+1:]...), nil
+		}
+		if c[i], err = deletePath(c[i], path[1:]); err != nil {
+			return nil, fmt.Errorf("%s/%w", path[0], err)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("cannot delete %s in %T", strings.Join(path, "/"), v)
+}
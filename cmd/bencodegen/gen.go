@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindBytes       // []byte holding raw bencode
+	kindInt
+	kindUint
+	kindStruct // a struct type generated together
+	kindPtr
+	kindSlice
+	kindMap // map with string keys
+	kindOther
+)
+
+// typ is a field type as far as the generator is concerned.
+type typ struct {
+	kind kind
+	name string // Go source of the type
+	elem *typ
+}
+
+// field is a struct field with its bencode key.
+type field struct {
+	name      string
+	key       string
+	omitempty bool
+	typ       *typ
+}
+
+type structType struct {
+	name   string
+	fields []field // sorted by key
+}
+
+var intTypes = map[string]kind{
+	"int": kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint,
+	"uintptr": kindUint, "byte": kindUint, "rune": kindInt,
+}
+
+// resolve describes the type expression e. structs holds the names of the
+// struct types generated together.
+func resolve(e ast.Expr, structs map[string]bool) *typ {
+	t := &typ{kind: kindOther, name: types.ExprString(e)}
+	switch e := e.(type) {
+	case *ast.Ident:
+		if e.Name == "string" {
+			t.kind = kindString
+		} else if k, ok := intTypes[e.Name]; ok {
+			t.kind = k
+		} else if structs[e.Name] {
+			t.kind = kindStruct
+		}
+	case *ast.StarExpr:
+		t.kind = kindPtr
+		t.elem = resolve(e.X, structs)
+	case *ast.ArrayType:
+		if e.Len != nil {
+			break
+		}
+		if id, ok := e.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			t.kind = kindBytes
+			break
+		}
+		t.kind = kindSlice
+		t.elem = resolve(e.Elt, structs)
+	case *ast.MapType:
+		if id, ok := e.Key.(*ast.Ident); ok && id.Name == "string" {
+			t.kind = kindMap
+			t.elem = resolve(e.Value, structs)
+		}
+	}
+	return t
+}
+
+// generate returns the source of the methods of the named struct types of
+// the file, or of its tagged struct types if names is empty.
+func generate(filename string, src []byte, names []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make(map[string]*ast.StructType)
+	var order []string
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if st, ok := ts.Type.(*ast.StructType); ok && ts.TypeParams == nil {
+				specs[ts.Name.Name] = st
+				order = append(order, ts.Name.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		for _, name := range order {
+			if hasTags(specs[name]) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, errors.New("no struct types with bencode tags")
+		}
+	}
+
+	structs := make(map[string]bool)
+	for _, name := range names {
+		if specs[name] == nil {
+			return nil, fmt.Errorf("struct type %s not found", name)
+		}
+		structs[name] = true
+	}
+	var sts []*structType
+	for _, name := range names {
+		st, err := newStructType(name, specs[name], structs)
+		if err != nil {
+			return nil, err
+		}
+		sts = append(sts, st)
+	}
+
+	g := &generator{imports: make(map[string]bool)}
+	for _, st := range sts {
+		g.marshal(st)
+		g.unmarshal(st)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by bencodegen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", f.Name.Name)
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString("\n\t\"github.com/ortymid/bencode\"\n)\n")
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func hasTags(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if f.Tag != nil {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			if _, ok := reflect.StructTag(tag).Lookup("bencode"); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newStructType(name string, st *ast.StructType, structs map[string]bool) (*structType, error) {
+	s := &structType{name: name}
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		tag = reflect.StructTag(tag).Get("bencode")
+		if tag == "-" {
+			continue
+		}
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded field %s is not supported", name, types.ExprString(f.Type))
+		}
+		opts := strings.Split(tag, ",")
+		for _, id := range f.Names {
+			if !id.IsExported() {
+				return nil, fmt.Errorf("%s.%s: struct field must be exported", name, id.Name)
+			}
+			fd := field{name: id.Name, key: opts[0], typ: resolve(f.Type, structs)}
+			if fd.key == "" {
+				fd.key = id.Name
+			}
+			for _, opt := range opts[1:] {
+				switch opt {
+				case "omitempty":
+					fd.omitempty = true
+				case "rest":
+					return nil, fmt.Errorf("%s.%s: rest fields are not supported", name, id.Name)
+				case "unixsec", "unixms", "seconds", "ms":
+					return nil, fmt.Errorf("%s.%s: time unit options are not supported", name, id.Name)
+				}
+			}
+			if fd.omitempty && fd.typ.kind == kindOther {
+				return nil, fmt.Errorf("%s.%s: omitempty is not supported for type %s", name, id.Name, fd.typ.name)
+			}
+			s.fields = append(s.fields, fd)
+		}
+	}
+	sort.SliceStable(s.fields, func(i, j int) bool { return s.fields[i].key < s.fields[j].key })
+	for i := 1; i < len(s.fields); i++ {
+		if s.fields[i].key == s.fields[i-1].key {
+			return nil, fmt.Errorf("%s: duplicate key %q", name, s.fields[i].key)
+		}
+	}
+	return s, nil
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	n       int // counter of temporary variables
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// tmp returns a new variable name.
+func (g *generator) tmp(prefix string) string {
+	g.n++
+	return prefix + strconv.Itoa(g.n)
+}
+
+func (g *generator) marshal(st *structType) {
+	g.printf("\n// MarshalBencode implements bencode.Marshaler.\n")
+	g.printf("func (x %s) MarshalBencode() ([]byte, error) {\n", st.name)
+	g.printf("return x.appendBencode(nil)\n}\n")
+
+	// the body is generated first to know whether err is used
+	body := g.buf.Len()
+	for _, f := range st.fields {
+		expr := "x." + f.name
+		if f.omitempty {
+			g.printf("if %s {\n", emptyCheck(expr, f.typ))
+		}
+		g.printf("b = append(b, %q...)\n", strconv.Itoa(len(f.key))+":"+f.key)
+		if f.omitempty && f.typ.kind == kindPtr {
+			g.encode("(*"+expr+")", f.typ.elem) // not nil
+		} else {
+			g.encode(expr, f.typ)
+		}
+		if f.omitempty {
+			g.printf("}\n")
+		}
+	}
+	code := append([]byte(nil), g.buf.Bytes()[body:]...)
+	g.buf.Truncate(body)
+
+	g.printf("\nfunc (x %s) appendBencode(b []byte) ([]byte, error) {\n", st.name)
+	if bytes.Contains(code, []byte("b, err = ")) {
+		g.printf("var err error\n")
+	}
+	g.printf("b = append(b, 'd')\n")
+	g.buf.Write(code)
+	g.printf("return append(b, 'e'), nil\n}\n")
+}
+
+// emptyCheck returns the condition under which a field with omitempty is
+// written.
+func emptyCheck(expr string, t *typ) string {
+	switch t.kind {
+	case kindString, kindBytes, kindSlice, kindMap:
+		return "len(" + expr + ") != 0"
+	case kindInt, kindUint:
+		return expr + " != 0"
+	case kindPtr:
+		return expr + " != nil"
+	}
+	return "true" // structs are never empty
+}
+
+// encode generates the code which appends expr of type t to b.
+func (g *generator) encode(expr string, t *typ) {
+	switch t.kind {
+	case kindString:
+		g.printf("b = bencode.String(%s).AppendBencode(b)\n", expr)
+	case kindBytes:
+		g.imports["errors"] = true
+		g.printf("if !bencode.Valid(%s) {\nreturn nil, errors.New(\"bencode: invalid raw bencode\")\n}\n", expr)
+		g.printf("b = append(b, %s...)\n", expr)
+	case kindInt:
+		g.printf("b = bencode.Int(%s).AppendBencode(b)\n", expr)
+	case kindUint:
+		if t.name == "uint64" || t.name == "uint" || t.name == "uintptr" {
+			g.imports["fmt"] = true
+			g.imports["math"] = true
+			g.printf("if uint64(%s) > math.MaxInt64 {\nreturn nil, fmt.Errorf(\"bencode: %%d overflows int64\", %s)\n}\n", expr, expr)
+		}
+		g.printf("b = bencode.Int(%s).AppendBencode(b)\n", expr)
+	case kindStruct:
+		g.printf("if b, err = %s.appendBencode(b); err != nil {\nreturn nil, err\n}\n", expr)
+	case kindPtr:
+		g.imports["errors"] = true
+		g.printf("if %s == nil {\nreturn nil, errors.New(%q)\n}\n", expr, "bencode: cannot encode nil "+t.name)
+		g.encode("(*"+expr+")", t.elem)
+	case kindSlice:
+		v := g.tmp("v")
+		g.printf("b = append(b, 'l')\nfor _, %s := range %s {\n", v, expr)
+		g.encode(v, t.elem)
+		g.printf("}\nb = append(b, 'e')\n")
+	case kindMap:
+		g.imports["sort"] = true
+		keys, k := g.tmp("keys"), g.tmp("k")
+		g.printf("%s := make([]string, 0, len(%s))\n", keys, expr)
+		g.printf("for %s := range %s {\n%s = append(%s, %s)\n}\n", k, expr, keys, keys, k)
+		g.printf("sort.Strings(%s)\nb = append(b, 'd')\n", keys)
+		g.printf("for _, %s := range %s {\nb = bencode.String(%s).AppendBencode(b)\n", k, keys, k)
+		g.encode(expr+"["+k+"]", t.elem)
+		g.printf("}\nb = append(b, 'e')\n")
+	default:
+		raw := g.tmp("raw")
+		g.printf("%s, err := bencode.Marshal(%s)\nif err != nil {\nreturn nil, err\n}\n", raw, expr)
+		g.printf("b = append(b, %s...)\n", raw)
+	}
+}
+
+func (g *generator) unmarshal(st *structType) {
+	g.printf("\n// UnmarshalBencode implements bencode.Unmarshaler.\n")
+	g.printf("func (x *%s) UnmarshalBencode(data []byte) error {\n", st.name)
+	g.printf("s := bencode.NewScanner(data)\n")
+	g.printf("if err := x.decodeBencode(s); err != nil {\nreturn err\n}\n")
+	g.printf("return s.Finish()\n}\n")
+
+	g.printf("\nfunc (x *%s) decodeBencode(s *bencode.Scanner) error {\n", st.name)
+	g.printf("if err := s.ReadDictStart(); err != nil {\nreturn err\n}\n")
+	g.printf("for s.More() {\nkey, err := s.ReadString()\nif err != nil {\nreturn err\n}\n")
+	g.printf("switch string(key) {\n")
+	for _, f := range st.fields {
+		g.printf("case %q:\n", f.key)
+		g.decode("x."+f.name, f.typ)
+	}
+	g.printf("default:\nif err := s.Skip(); err != nil {\nreturn err\n}\n")
+	g.printf("}\n}\nreturn s.ReadEnd()\n}\n")
+}
+
+// decode generates the code which reads a value of type t into target.
+func (g *generator) decode(target string, t *typ) {
+	switch t.kind {
+	case kindString:
+		v := g.tmp("v")
+		g.printf("%s, err := s.ReadString()\nif err != nil {\nreturn err\n}\n", v)
+		g.printf("%s = string(%s)\n", target, v)
+	case kindBytes:
+		v := g.tmp("v")
+		g.printf("%s, err := s.ReadRaw()\nif err != nil {\nreturn err\n}\n", v)
+		g.printf("%s = append([]byte(nil), %s...)\n", target, v)
+	case kindInt, kindUint:
+		v := g.tmp("v")
+		g.printf("%s, err := s.ReadInt()\nif err != nil {\nreturn err\n}\n", v)
+		switch {
+		case t.kind == kindInt && t.name != "int64":
+			g.imports["fmt"] = true
+			g.printf("if int64(%s(%s)) != %s {\nreturn fmt.Errorf(\"bencode: %%d overflows %s\", %s)\n}\n", t.name, v, v, t.name, v)
+		case t.name == "uint64":
+			g.imports["fmt"] = true
+			g.printf("if %s < 0 {\nreturn fmt.Errorf(\"bencode: %%d overflows %s\", %s)\n}\n", v, t.name, v)
+		case t.kind == kindUint:
+			g.imports["fmt"] = true
+			g.printf("if %s < 0 || uint64(%s(%s)) != uint64(%s) {\nreturn fmt.Errorf(\"bencode: %%d overflows %s\", %s)\n}\n", v, t.name, v, v, t.name, v)
+		}
+		g.printf("%s = %s(%s)\n", target, t.name, v)
+	case kindStruct:
+		g.printf("if err := %s.decodeBencode(s); err != nil {\nreturn err\n}\n", target)
+	case kindPtr:
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", target, target, t.elem.name)
+		g.decode("(*"+target+")", t.elem)
+	case kindSlice:
+		v := g.tmp("v")
+		g.printf("if err := s.ReadListStart(); err != nil {\nreturn err\n}\n")
+		g.printf("%s = %s[:0]\nfor s.More() {\nvar %s %s\n", target, target, v, t.elem.name)
+		g.decode(v, t.elem)
+		g.printf("%s = append(%s, %s)\n}\n", target, target, v)
+		g.printf("if err := s.ReadEnd(); err != nil {\nreturn err\n}\n")
+	case kindMap:
+		k, v := g.tmp("k"), g.tmp("v")
+		g.printf("if err := s.ReadDictStart(); err != nil {\nreturn err\n}\n")
+		g.printf("if %s == nil {\n%s = make(%s)\n}\n", target, target, t.name)
+		g.printf("for s.More() {\n%s, err := s.ReadString()\nif err != nil {\nreturn err\n}\nvar %s %s\n", k, v, t.elem.name)
+		g.decode(v, t.elem)
+		g.printf("%s[string(%s)] = %s\n}\n", target, k, v)
+		g.printf("if err := s.ReadEnd(); err != nil {\nreturn err\n}\n")
+	default:
+		v := g.tmp("raw")
+		g.printf("%s, err := s.ReadRaw()\nif err != nil {\nreturn err\n}\n", v)
+		g.printf("if err := bencode.Unmarshal(%s, &%s); err != nil {\nreturn err\n}\n", v, target)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestGenerateGolden checks that the generated code of the test package is
+// up to date.
+func TestGenerateGolden(t *testing.T) {
+	src, err := os.ReadFile("internal/gentest/types.go")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want, err := os.ReadFile("internal/gentest/types_bencode.go")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	got, err := generate("types.go", src, []string{"Message", "Args", "Item"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(got) != string(want) {
+		t.Error("generated code differs from internal/gentest/types_bencode.go, run go generate")
+	}
+}
+
+func TestGenerateTagged(t *testing.T) {
+	src := `package p
+
+type A struct {
+	X int ` + "`bencode:\"x\"`" + `
+}
+
+type B struct {
+	Y int
+}
+`
+	got, err := generate("p.go", []byte(src), nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !strings.Contains(string(got), "func (x A) MarshalBencode()") || strings.Contains(string(got), "func (x B)") {
+		t.Errorf("\ngot: %s \nwant: methods of A only", got)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		types []string
+		want  string
+	}{
+		{"No tags", "package p\ntype A struct{ X int }", nil, "no struct types with bencode tags"},
+		{"Not found", "package p\ntype A struct{ X int }", []string{"B"}, "struct type B not found"},
+		{"Unexported", "package p\ntype A struct{ x int }", []string{"A"}, "A.x: struct field must be exported"},
+		{"Embedded", "package p\ntype B struct{}\ntype A struct{ B }", []string{"A"}, "A: embedded field B is not supported"},
+		{"Omitempty", "package p\ntype A struct{ X [2]int `bencode:\",omitempty\"` }", []string{"A"}, "A.X: omitempty is not supported for type [2]int"},
+		{"Rest", "package p\ntype A struct{ X map[string][]byte `bencode:\",rest\"` }", []string{"A"}, "A.X: rest fields are not supported"},
+		{"Time unit", "package p\nimport \"time\"\ntype A struct{ X time.Duration `bencode:\",seconds\"` }", []string{"A"}, "A.X: time unit options are not supported"},
+		{"Duplicate", "package p\ntype A struct{ X int `bencode:\"k\"`; Y int `bencode:\"k\"` }", []string{"A"}, `A: duplicate key "k"`},
+		{"Syntax", "package p\ntype A struct{", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := generate("p.go", []byte(test.src), test.types)
+			if err == nil {
+				t.Fatal("no error for", test.src)
+			}
+			if test.want != "" && err.Error() != test.want {
+				t.Error("got:", err, "want:", test.want)
+			}
+		})
+	}
+}
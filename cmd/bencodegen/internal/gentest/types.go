@@ -0,0 +1,41 @@
+// Package gentest holds types with methods generated by bencodegen. Its
+// tests compare them with the reflection based encoding.
+package gentest
+
+//go:generate go run github.com/ortymid/bencode/cmd/bencodegen -type Message,Args,Item $GOFILE
+
+// Message has fields of every kind the generator handles.
+type Message struct {
+	T       string            `bencode:"t"`
+	Y       string            `bencode:"y"`
+	A       *Args             `bencode:"a,omitempty"`
+	Items   []Item            `bencode:"items,omitempty"`
+	Counts  map[string]int    `bencode:"counts,omitempty"`
+	Nested  [][]string        `bencode:"nested,omitempty"`
+	Raw     []byte            `bencode:"raw,omitempty"`
+	Flag    uint8             `bencode:"flag,omitempty"`
+	Big     uint64            `bencode:"big,omitempty"`
+	Count   uint              `bencode:"count,omitempty"`
+	Small   int8              `bencode:"small,omitempty"`
+	Ptr     *int              `bencode:"ptr,omitempty"`
+	Other   Code              `bencode:"other"`
+	Extra   map[string]Item   `bencode:"extra,omitempty"`
+	Skipped string            `bencode:"-"`
+	Item    Item              `bencode:"item"`
+	Tags    map[string]string `bencode:"tags,omitempty"`
+}
+
+// Args is a nested struct.
+type Args struct {
+	ID   string `bencode:"id"`
+	Port int    `bencode:"port,omitempty"`
+}
+
+// Item has no tags, so the field names are the keys.
+type Item struct {
+	Name string
+	Size int64
+}
+
+// Code is encoded through bencode.Marshal, as it is not generated.
+type Code int
@@ -0,0 +1,481 @@
+// Code generated by bencodegen; DO NOT EDIT.
+
+package gentest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ortymid/bencode"
+)
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Message) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Message) appendBencode(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, 'd')
+	if x.A != nil {
+		b = append(b, "1:a"...)
+		if b, err = (*x.A).appendBencode(b); err != nil {
+			return nil, err
+		}
+	}
+	if x.Big != 0 {
+		b = append(b, "3:big"...)
+		if uint64(x.Big) > math.MaxInt64 {
+			return nil, fmt.Errorf("bencode: %d overflows int64", x.Big)
+		}
+		b = bencode.Int(x.Big).AppendBencode(b)
+	}
+	if x.Count != 0 {
+		b = append(b, "5:count"...)
+		if uint64(x.Count) > math.MaxInt64 {
+			return nil, fmt.Errorf("bencode: %d overflows int64", x.Count)
+		}
+		b = bencode.Int(x.Count).AppendBencode(b)
+	}
+	if len(x.Counts) != 0 {
+		b = append(b, "6:counts"...)
+		keys1 := make([]string, 0, len(x.Counts))
+		for k2 := range x.Counts {
+			keys1 = append(keys1, k2)
+		}
+		sort.Strings(keys1)
+		b = append(b, 'd')
+		for _, k2 := range keys1 {
+			b = bencode.String(k2).AppendBencode(b)
+			b = bencode.Int(x.Counts[k2]).AppendBencode(b)
+		}
+		b = append(b, 'e')
+	}
+	if len(x.Extra) != 0 {
+		b = append(b, "5:extra"...)
+		keys3 := make([]string, 0, len(x.Extra))
+		for k4 := range x.Extra {
+			keys3 = append(keys3, k4)
+		}
+		sort.Strings(keys3)
+		b = append(b, 'd')
+		for _, k4 := range keys3 {
+			b = bencode.String(k4).AppendBencode(b)
+			if b, err = x.Extra[k4].appendBencode(b); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, 'e')
+	}
+	if x.Flag != 0 {
+		b = append(b, "4:flag"...)
+		b = bencode.Int(x.Flag).AppendBencode(b)
+	}
+	b = append(b, "4:item"...)
+	if b, err = x.Item.appendBencode(b); err != nil {
+		return nil, err
+	}
+	if len(x.Items) != 0 {
+		b = append(b, "5:items"...)
+		b = append(b, 'l')
+		for _, v5 := range x.Items {
+			if b, err = v5.appendBencode(b); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, 'e')
+	}
+	if len(x.Nested) != 0 {
+		b = append(b, "6:nested"...)
+		b = append(b, 'l')
+		for _, v6 := range x.Nested {
+			b = append(b, 'l')
+			for _, v7 := range v6 {
+				b = bencode.String(v7).AppendBencode(b)
+			}
+			b = append(b, 'e')
+		}
+		b = append(b, 'e')
+	}
+	b = append(b, "5:other"...)
+	raw8, err := bencode.Marshal(x.Other)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, raw8...)
+	if x.Ptr != nil {
+		b = append(b, "3:ptr"...)
+		b = bencode.Int((*x.Ptr)).AppendBencode(b)
+	}
+	if len(x.Raw) != 0 {
+		b = append(b, "3:raw"...)
+		if !bencode.Valid(x.Raw) {
+			return nil, errors.New("bencode: invalid raw bencode")
+		}
+		b = append(b, x.Raw...)
+	}
+	if x.Small != 0 {
+		b = append(b, "5:small"...)
+		b = bencode.Int(x.Small).AppendBencode(b)
+	}
+	b = append(b, "1:t"...)
+	b = bencode.String(x.T).AppendBencode(b)
+	if len(x.Tags) != 0 {
+		b = append(b, "4:tags"...)
+		keys9 := make([]string, 0, len(x.Tags))
+		for k10 := range x.Tags {
+			keys9 = append(keys9, k10)
+		}
+		sort.Strings(keys9)
+		b = append(b, 'd')
+		for _, k10 := range keys9 {
+			b = bencode.String(k10).AppendBencode(b)
+			b = bencode.String(x.Tags[k10]).AppendBencode(b)
+		}
+		b = append(b, 'e')
+	}
+	b = append(b, "1:y"...)
+	b = bencode.String(x.Y).AppendBencode(b)
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Message) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Message) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "a":
+			if x.A == nil {
+				x.A = new(Args)
+			}
+			if err := (*x.A).decodeBencode(s); err != nil {
+				return err
+			}
+		case "big":
+			v11, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if v11 < 0 {
+				return fmt.Errorf("bencode: %d overflows uint64", v11)
+			}
+			x.Big = uint64(v11)
+		case "count":
+			v12, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if v12 < 0 || uint64(uint(v12)) != uint64(v12) {
+				return fmt.Errorf("bencode: %d overflows uint", v12)
+			}
+			x.Count = uint(v12)
+		case "counts":
+			if err := s.ReadDictStart(); err != nil {
+				return err
+			}
+			if x.Counts == nil {
+				x.Counts = make(map[string]int)
+			}
+			for s.More() {
+				k13, err := s.ReadString()
+				if err != nil {
+					return err
+				}
+				var v14 int
+				v15, err := s.ReadInt()
+				if err != nil {
+					return err
+				}
+				if int64(int(v15)) != v15 {
+					return fmt.Errorf("bencode: %d overflows int", v15)
+				}
+				v14 = int(v15)
+				x.Counts[string(k13)] = v14
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		case "extra":
+			if err := s.ReadDictStart(); err != nil {
+				return err
+			}
+			if x.Extra == nil {
+				x.Extra = make(map[string]Item)
+			}
+			for s.More() {
+				k16, err := s.ReadString()
+				if err != nil {
+					return err
+				}
+				var v17 Item
+				if err := v17.decodeBencode(s); err != nil {
+					return err
+				}
+				x.Extra[string(k16)] = v17
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		case "flag":
+			v18, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if v18 < 0 || uint64(uint8(v18)) != uint64(v18) {
+				return fmt.Errorf("bencode: %d overflows uint8", v18)
+			}
+			x.Flag = uint8(v18)
+		case "item":
+			if err := x.Item.decodeBencode(s); err != nil {
+				return err
+			}
+		case "items":
+			if err := s.ReadListStart(); err != nil {
+				return err
+			}
+			x.Items = x.Items[:0]
+			for s.More() {
+				var v19 Item
+				if err := v19.decodeBencode(s); err != nil {
+					return err
+				}
+				x.Items = append(x.Items, v19)
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		case "nested":
+			if err := s.ReadListStart(); err != nil {
+				return err
+			}
+			x.Nested = x.Nested[:0]
+			for s.More() {
+				var v20 []string
+				if err := s.ReadListStart(); err != nil {
+					return err
+				}
+				v20 = v20[:0]
+				for s.More() {
+					var v21 string
+					v22, err := s.ReadString()
+					if err != nil {
+						return err
+					}
+					v21 = string(v22)
+					v20 = append(v20, v21)
+				}
+				if err := s.ReadEnd(); err != nil {
+					return err
+				}
+				x.Nested = append(x.Nested, v20)
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		case "other":
+			raw23, err := s.ReadRaw()
+			if err != nil {
+				return err
+			}
+			if err := bencode.Unmarshal(raw23, &x.Other); err != nil {
+				return err
+			}
+		case "ptr":
+			if x.Ptr == nil {
+				x.Ptr = new(int)
+			}
+			v24, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if int64(int(v24)) != v24 {
+				return fmt.Errorf("bencode: %d overflows int", v24)
+			}
+			(*x.Ptr) = int(v24)
+		case "raw":
+			v25, err := s.ReadRaw()
+			if err != nil {
+				return err
+			}
+			x.Raw = append([]byte(nil), v25...)
+		case "small":
+			v26, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if int64(int8(v26)) != v26 {
+				return fmt.Errorf("bencode: %d overflows int8", v26)
+			}
+			x.Small = int8(v26)
+		case "t":
+			v27, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.T = string(v27)
+		case "tags":
+			if err := s.ReadDictStart(); err != nil {
+				return err
+			}
+			if x.Tags == nil {
+				x.Tags = make(map[string]string)
+			}
+			for s.More() {
+				k28, err := s.ReadString()
+				if err != nil {
+					return err
+				}
+				var v29 string
+				v30, err := s.ReadString()
+				if err != nil {
+					return err
+				}
+				v29 = string(v30)
+				x.Tags[string(k28)] = v29
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		case "y":
+			v31, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Y = string(v31)
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Args) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Args) appendBencode(b []byte) ([]byte, error) {
+	b = append(b, 'd')
+	b = append(b, "2:id"...)
+	b = bencode.String(x.ID).AppendBencode(b)
+	if x.Port != 0 {
+		b = append(b, "4:port"...)
+		b = bencode.Int(x.Port).AppendBencode(b)
+	}
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Args) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Args) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "id":
+			v32, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.ID = string(v32)
+		case "port":
+			v33, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if int64(int(v33)) != v33 {
+				return fmt.Errorf("bencode: %d overflows int", v33)
+			}
+			x.Port = int(v33)
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Item) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Item) appendBencode(b []byte) ([]byte, error) {
+	b = append(b, 'd')
+	b = append(b, "4:Name"...)
+	b = bencode.String(x.Name).AppendBencode(b)
+	b = append(b, "4:Size"...)
+	b = bencode.Int(x.Size).AppendBencode(b)
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Item) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Item) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "Name":
+			v34, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Name = string(v34)
+		case "Size":
+			v35, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			x.Size = int64(v35)
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
@@ -0,0 +1,144 @@
+package gentest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ortymid/bencode"
+)
+
+// plain has the fields of Message without the generated methods, so that
+// bencode.Marshal and bencode.Unmarshal use reflection for it.
+type plain Message
+
+func newMessage() *Message {
+	n := -7
+	return &Message{
+		T:      "aa",
+		Y:      "q",
+		A:      &Args{ID: "abcdefghij0123456789", Port: 6881},
+		Items:  []Item{{"x", 1}, {"y", 2}},
+		Counts: map[string]int{"b": 2, "a": 1},
+		Nested: [][]string{{"a", "b"}, nil},
+		Raw:    []byte("d1:xi1ee"),
+		Flag:   255,
+		Big:    1 << 40,
+		Count:  7,
+		Small:  -128,
+		Ptr:    &n,
+		Other:  3,
+		Extra:  map[string]Item{"z": {"z", 0}},
+		Item:   Item{"i", 9},
+		Tags:   map[string]string{"k": "v"},
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *Message
+	}{
+		{"Full", newMessage()},
+		{"Empty", &Message{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.msg.MarshalBencode()
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			want, err := bencode.Marshal((*plain)(test.msg))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("\ngot: %s \nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	want := newMessage()
+	data, err := bencode.Marshal(want)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	got := &Message{}
+	if err := got.UnmarshalBencode(append(data[:len(data)-1:len(data)-1], "7:unknownld1:xleeee"...)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, want)
+	}
+
+	// through the decoder, which calls UnmarshalBencode
+	got = &Message{}
+	if err := bencode.Unmarshal(data, got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, want)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Not a dict", "le"},
+		{"Wrong type", "d1:ti1ee"},
+		{"Overflow", "d5:smalli128ee"},
+		{"Negative uint", "d3:bigi-1ee"},
+		{"Nested", "d1:ad2:idi1eee"},
+		{"Unterminated", "d1:t1:a"},
+		{"Trailing data", "d1:t1:aei1e"},
+		{"Other", "d5:other1:xe"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := new(Message).UnmarshalBencode([]byte(test.input)); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"Raw", Message{Raw: []byte("d1:x")}},
+		{"Overflow", Message{Big: 1 << 63}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.msg.MarshalBencode(); err == nil {
+				t.Error("no error for", test.msg)
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	data, _ := bencode.Marshal(Args{ID: "abcdefghij0123456789", Port: 6881})
+	b.Run("Generated", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var a Args
+			a.UnmarshalBencode(data)
+		}
+	})
+	b.Run("Reflection", func(b *testing.B) {
+		type plainArgs Args
+		for i := 0; i < b.N; i++ {
+			var a plainArgs
+			bencode.Unmarshal(data, &a)
+		}
+	})
+}
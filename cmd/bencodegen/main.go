@@ -0,0 +1,69 @@
+// Command bencodegen generates MarshalBencode and UnmarshalBencode methods
+// for struct types, which encode and decode without reflection and without
+// an intermediate Value tree.
+//
+// Usage:
+//
+//	bencodegen [-type T1,T2] [-o output] file.go
+//
+// It is meant to be run by go generate:
+//
+//	//go:generate bencodegen -type Message,Args $GOFILE
+//
+// Without -type, methods are generated for every struct type of the file
+// which has a field with a bencode tag. The output defaults to the input
+// file name with the _bencode.go suffix.
+//
+// Fields follow the rules of bencode.Marshal: the key is the tag name or
+// the field name, "-" skips a field and omitempty leaves out empty values.
+// Strings, integers, []byte holding raw bencode, slices, maps with string
+// keys, pointers and the struct types generated together are handled
+// directly. Values of other types are encoded and decoded with
+// bencode.Marshal and bencode.Unmarshal.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma separated `list` of type names; defaults to the tagged struct types")
+	output := flag.String("o", "", "output `file`; defaults to the input with the _bencode.go suffix")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bencodegen [-type T1,T2] [-o output] file.go")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+	var names []string
+	if *types != "" {
+		names = strings.Split(*types, ",")
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(input, ".go") + "_bencode.go"
+	}
+	if err := run(input, *output, names); err != nil {
+		fmt.Fprintln(os.Stderr, "bencodegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, output string, names []string) error {
+	src, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	code, err := generate(input, src, names)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, code, 0o644)
+}
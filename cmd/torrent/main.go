@@ -0,0 +1,370 @@
+// Command torrent inspects, creates and edits .torrent files.
+//
+// Usage:
+//
+//	torrent info file
+//	torrent create [-o file] [-piece-length n] [-private] [-name s] [-version 1|2|hybrid]
+//	               [-tracker url]... [-comment s] [-ignore pattern]... path
+//	torrent verify [-workers n] file dir
+//	torrent magnet file
+//	torrent trackers [-add url]... [-remove url]... [-replace] [-o file] file
+//
+// The trackers command edits the file in place unless -o is given. It keeps
+// the info dict and every other key byte for byte, so the info-hash does
+// not change.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ortymid/bencode/magnet"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+const usage = `usage: torrent <command> [flags] [args]
+
+commands:
+  info      print the content of a torrent
+  create    create a torrent from a file or directory
+  verify    check local data against a torrent
+  magnet    print the magnet link of a torrent
+  trackers  print or edit the trackers of a torrent`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "torrent:", err)
+		os.Exit(1)
+	}
+}
+
+type command func(args []string, stdout io.Writer) error
+
+var commands = map[string]command{
+	"info":     info,
+	"create":   create,
+	"verify":   verify,
+	"magnet":   printMagnet,
+	"trackers": trackers,
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return cmd(args[1:], stdout)
+}
+
+// parseFlags parses the flags of a command and checks the number of the
+// remaining arguments.
+func parseFlags(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, fmt.Errorf("%s: wrong number of arguments", fs.Name())
+	}
+	return fs.Args(), nil
+}
+
+// stringList is a flag which can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// size is a flag of a size in bytes with an optional k, m or g suffix.
+type size int64
+
+func (s *size) String() string { return strconv.FormatInt(int64(*s), 10) }
+
+func (s *size) Set(v string) error {
+	if v == "" {
+		return fmt.Errorf("invalid size %q", v)
+	}
+	shift := 0
+	switch strings.ToLower(v[len(v)-1:]) {
+	case "k":
+		shift = 10
+	case "m":
+		shift = 20
+	case "g":
+		shift = 30
+	}
+	if shift > 0 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", v)
+	}
+	*s = size(n << shift)
+	return nil
+}
+
+func info(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	args, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	mi, err := metainfo.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	info := &mi.Info
+
+	version := "v1"
+	switch {
+	case info.IsHybrid():
+		version = "hybrid"
+	case info.HasV2():
+		version = "v2"
+	}
+	files, err := fileList(info)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.length
+	}
+
+	fmt.Fprintf(stdout, "name:          %s\n", info.Name)
+	fmt.Fprintf(stdout, "version:       %s\n", version)
+	fmt.Fprintf(stdout, "size:          %d\n", total)
+	fmt.Fprintf(stdout, "piece length:  %d\n", info.PieceLength)
+	fmt.Fprintf(stdout, "pieces:        %d\n", info.NumPieces())
+	fmt.Fprintf(stdout, "private:       %t\n", info.Private == 1)
+	if info.HasV1() {
+		h, err := mi.InfoHashV1()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "info-hash v1:  %s\n", h)
+	}
+	if info.HasV2() {
+		h, err := mi.InfoHashV2()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "info-hash v2:  %s\n", h)
+	}
+	if mi.Comment != "" {
+		fmt.Fprintf(stdout, "comment:       %s\n", mi.Comment)
+	}
+	if mi.CreatedBy != "" {
+		fmt.Fprintf(stdout, "created by:    %s\n", mi.CreatedBy)
+	}
+	for i, tier := range announceTiers(mi) {
+		fmt.Fprintf(stdout, "tracker %d:     %s\n", i, strings.Join(tier, " "))
+	}
+	fmt.Fprintln(stdout, "files:")
+	for _, f := range files {
+		fmt.Fprintf(stdout, "  %12d  %s\n", f.length, strings.Join(f.path, "/"))
+	}
+	return nil
+}
+
+type file struct {
+	path   []string
+	length int64
+}
+
+// fileList returns the files of a torrent without padding files, with
+// their paths relative to the content directory.
+func fileList(info *metainfo.Info) ([]file, error) {
+	var files []file
+	switch {
+	case info.Length != nil:
+		files = append(files, file{[]string{info.Name}, *info.Length})
+	case info.HasV1():
+		for _, f := range info.Files {
+			if !f.IsPadding() {
+				files = append(files, file{append([]string{info.Name}, f.Path...), f.Length})
+			}
+		}
+	default:
+		v2, err := info.FilesV2()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range v2 {
+			path, err := info.FilePath(f.Path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file{path, f.Length})
+		}
+	}
+	return files, nil
+}
+
+// announceTiers returns the trackers of mi as tiers, with the announce URL
+// as the only tier if there is no announce-list.
+func announceTiers(mi *metainfo.MetaInfo) [][]string {
+	if len(mi.AnnounceList) > 0 {
+		return mi.AnnounceList
+	}
+	if mi.Announce != "" {
+		return [][]string{{mi.Announce}}
+	}
+	return nil
+}
+
+var versions = map[string]metainfo.Version{
+	"1":      metainfo.Version1,
+	"2":      metainfo.Version2,
+	"hybrid": metainfo.VersionHybrid,
+}
+
+func create(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	var (
+		pieceLength size
+		trackers    stringList
+		ignore      stringList
+	)
+	out := fs.String("o", "", "output `file`, defaults to the name with a .torrent extension")
+	fs.Var(&pieceLength, "piece-length", "piece length, such as 256k; chosen automatically by default")
+	private := fs.Bool("private", false, "set the private flag")
+	name := fs.String("name", "", "torrent name, defaults to the base name of the path")
+	version := fs.String("version", "1", "metadata version: 1, 2 or hybrid")
+	fs.Var(&trackers, "tracker", "announce `url`, each in its own tier; may be repeated")
+	comment := fs.String("comment", "", "comment")
+	fs.Var(&ignore, "ignore", "skip files matching the `pattern`; may be repeated")
+	args, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	v, ok := versions[*version]
+	if !ok {
+		return fmt.Errorf("create: unknown version %q", *version)
+	}
+
+	b := &metainfo.Builder{
+		PieceLength: int64(pieceLength),
+		Name:        *name,
+		Private:     *private,
+		Ignore:      ignore,
+		Version:     v,
+	}
+	mi, err := b.Build(args[0])
+	if err != nil {
+		return err
+	}
+	mi.Comment = *comment
+	mi.CreatedBy = "torrent"
+	setTrackers(mi, trackersTiers(trackers))
+
+	if *out == "" {
+		*out = mi.Info.Name + ".torrent"
+	}
+	if err := writeFile(*out, mi); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, *out)
+	return nil
+}
+
+// trackersTiers puts each tracker in its own tier.
+func trackersTiers(trackers []string) [][]string {
+	var tiers [][]string
+	for _, tr := range trackers {
+		tiers = append(tiers, []string{tr})
+	}
+	return tiers
+}
+
+// setTrackers sets announce to the first tracker and announce-list to the
+// tiers if there is more than one tracker.
+func setTrackers(mi *metainfo.MetaInfo, tiers [][]string) {
+	mi.Announce, mi.AnnounceList = "", nil
+	n := 0
+	for _, tier := range tiers {
+		n += len(tier)
+	}
+	if n == 0 {
+		return
+	}
+	mi.Announce = tiers[0][0]
+	if n > 1 {
+		mi.AnnounceList = tiers
+	}
+}
+
+func writeFile(name string, mi *metainfo.MetaInfo) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := mi.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func verify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	workers := fs.Int("workers", 0, "number of hashing goroutines, defaults to the number of CPUs")
+	args, err := parseFlags(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	mi, err := metainfo.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := mi.NumPieces()
+	if err != nil {
+		return err
+	}
+	good, err := mi.Verify(args[1], metainfo.VerifyOptions{Workers: *workers})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%d of %d pieces ok\n", good.Count(), n)
+	if good.Count() != n {
+		var bad []string
+		for i := 0; i < n; i++ {
+			if !good.Has(i) {
+				bad = append(bad, strconv.Itoa(i))
+			}
+		}
+		return fmt.Errorf("bad pieces: %s", strings.Join(bad, " "))
+	}
+	return nil
+}
+
+func printMagnet(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("magnet", flag.ContinueOnError)
+	args, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	mi, err := metainfo.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	m, err := magnet.FromMetaInfo(mi)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, m)
+	return err
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// testTorrent creates a torrent of a directory with two files and returns
+// the paths of the torrent and of the directory holding the content.
+func testTorrent(t *testing.T, args ...string) (torrent, dir string) {
+	dir = t.TempDir()
+	content := filepath.Join(dir, "spam")
+	if err := os.MkdirAll(filepath.Join(content, "sub"), 0o755); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	files := map[string]string{"a": strings.Repeat("a", 40000), "sub/b": "bbb"}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(content, name), []byte(data), 0o644); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	torrent = filepath.Join(dir, "spam.torrent")
+	args = append([]string{"create", "-o", torrent, "-piece-length", "16k"}, args...)
+	var out bytes.Buffer
+	if err := run(append(args, content), &out); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return torrent, dir
+}
+
+func TestCreateInfo(t *testing.T) {
+	torrent, _ := testTorrent(t, "-version", "hybrid", "-private", "-tracker", "http://a/announce", "-tracker", "http://b/announce")
+
+	var out bytes.Buffer
+	if err := run([]string{"info", torrent}, &out); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, want := range []string{
+		"name:          spam\n",
+		"version:       hybrid\n",
+		"size:          40003\n",
+		"piece length:  16384\n",
+		"private:       true\n",
+		"info-hash v1:  ",
+		"info-hash v2:  ",
+		"tracker 0:     http://a/announce\n",
+		"tracker 1:     http://b/announce\n",
+		"         40000  spam/a\n",
+		"             3  spam/sub/b\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("\ngot: %s \nwant: %q", out.String(), want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, version := range []string{"1", "2"} {
+		t.Run(version, func(t *testing.T) {
+			torrent, dir := testTorrent(t, "-version", version)
+
+			var out bytes.Buffer
+			if err := run([]string{"verify", torrent, dir}, &out); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "spam", "a"), []byte("x"), 0o644); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if err := run([]string{"verify", torrent, dir}, &out); err == nil {
+				t.Error("no error for corrupt data")
+			}
+		})
+	}
+}
+
+func TestMagnet(t *testing.T) {
+	torrent, _ := testTorrent(t, "-tracker", "http://a/announce")
+	mi, err := metainfo.LoadFile(torrent)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	h, err := mi.InfoHashV1()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"magnet", torrent}, &out); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := "magnet:?xt=urn:btih:" + h.String() + "&dn=spam&tr=http%3A%2F%2Fa%2Fannounce\n"
+	if out.String() != want {
+		t.Error("got:", out.String(), "want:", want)
+	}
+}
+
+func TestTrackers(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Add", []string{"-add", "http://c"}, "0: http://a\n1: http://b\n2: http://c\n"},
+		{"Add existing", []string{"-add", "http://a"}, "0: http://a\n1: http://b\n"},
+		{"Remove", []string{"-remove", "http://a"}, "0: http://b\n"},
+		{"Remove all", []string{"-remove", "http://a", "-remove", "http://b"}, ""},
+		{"Replace", []string{"-replace", "-add", "http://c"}, "0: http://c\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			torrent, _ := testTorrent(t, "-tracker", "http://a", "-tracker", "http://b")
+			addKey(t, torrent, "url-list", "l8:http://we")
+			if err := os.Chmod(torrent, 0o600); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			before, err := metainfo.LoadFile(torrent)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			var out bytes.Buffer
+			if err := run(append(append([]string{"trackers"}, test.args...), torrent), &out); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if err := run([]string{"trackers", torrent}, &out); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if out.String() != test.want {
+				t.Errorf("\ngot: %q \nwant: %q", out.String(), test.want)
+			}
+
+			after, err := metainfo.LoadFile(torrent)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			hashBefore, _ := before.InfoHashV1()
+			hashAfter, _ := after.InfoHashV1()
+			if hashBefore != hashAfter {
+				t.Error("got:", hashAfter, "want:", hashBefore)
+			}
+			if data, _ := os.ReadFile(torrent); !bytes.Contains(data, []byte("8:url-listl8:http://we")) {
+				t.Error("got: unknown key lost")
+			}
+			fi, err := os.Stat(torrent)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if fi.Mode().Perm() != 0o600 {
+				t.Error("got:", fi.Mode().Perm(), "want:", os.FileMode(0o600))
+			}
+			if after.Comment != before.Comment || after.CreatedBy != before.CreatedBy {
+				t.Errorf("\ngot: %+v \nwant: %+v", after, before)
+			}
+		})
+	}
+}
+
+// addKey adds a key with a raw bencoded value to the metainfo in the named
+// file.
+func addKey(t *testing.T, name, key, value string) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var m map[string][]byte
+	if err := bencode.Unmarshal(data, &m); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	m[key] = []byte(value)
+	if data, err = bencode.Marshal(m); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestSizeFlag(t *testing.T) {
+	tests := []struct {
+		input string
+		want  size
+	}{
+		{"1000", 1000},
+		{"16k", 16 << 10},
+		{"4M", 4 << 20},
+		{"1g", 1 << 30},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got size
+			if err := got.Set(test.input); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got != test.want {
+				t.Error("got:", got, "want:", test.want)
+			}
+		})
+	}
+}
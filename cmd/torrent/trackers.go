@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+func trackers(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("trackers", flag.ContinueOnError)
+	var add, remove stringList
+	fs.Var(&add, "add", "add a tracker `url` in a new tier; may be repeated")
+	fs.Var(&remove, "remove", "remove a tracker `url` from every tier; may be repeated")
+	replace := fs.Bool("replace", false, "remove all trackers before adding")
+	out := fs.String("o", "", "output `file`, defaults to editing the file in place")
+	args, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	tiers := announceTiers(mi)
+
+	if len(add) == 0 && len(remove) == 0 && !*replace {
+		for i, tier := range tiers {
+			fmt.Fprintf(stdout, "%d: %s\n", i, strings.Join(tier, " "))
+		}
+		return nil
+	}
+
+	if *replace {
+		tiers = nil
+	}
+	tiers = removeTrackers(tiers, remove)
+	for _, tr := range add {
+		if !hasTracker(tiers, tr) {
+			tiers = append(tiers, []string{tr})
+		}
+	}
+	setTrackers(mi, tiers)
+
+	edited, err := editTrackers(data, mi)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = args[0]
+	}
+	return writeAtomic(*out, edited, fi.Mode().Perm())
+}
+
+func removeTrackers(tiers [][]string, remove []string) [][]string {
+	var out [][]string
+	for _, tier := range tiers {
+		var kept []string
+		for _, tr := range tier {
+			if !contains(remove, tr) {
+				kept = append(kept, tr)
+			}
+		}
+		if len(kept) > 0 {
+			out = append(out, kept)
+		}
+	}
+	return out
+}
+
+func hasTracker(tiers [][]string, tr string) bool {
+	for _, tier := range tiers {
+		if contains(tier, tr) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// editTrackers replaces the announce keys of the metainfo in data with the
+// ones of mi. The other keys, the info dict in particular, keep their
+// exact bytes.
+func editTrackers(data []byte, mi *metainfo.MetaInfo) ([]byte, error) {
+	var m map[string][]byte
+	if err := bencode.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "announce")
+	delete(m, "announce-list")
+	if mi.Announce != "" {
+		m["announce"] = bencode.String(mi.Announce).Bencode()
+	}
+	if len(mi.AnnounceList) > 0 {
+		b, err := bencode.Marshal(mi.AnnounceList)
+		if err != nil {
+			return nil, err
+		}
+		m["announce-list"] = b
+	}
+	return bencode.Marshal(m)
+}
+
+// writeAtomic replaces the named file with data, giving it the permissions
+// perm.
+func writeAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".torrent-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -1,10 +1,14 @@
 package bencode
 
 import (
-	"bytes"
+	"bufio"
 	"fmt"
 	"io"
+	"math/big"
 	"reflect"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // ErrInvalidArgument describes an error which occurs when an invalid
@@ -23,26 +27,115 @@ func (e *ErrInvalidArgument) Is(err error) bool {
 	return ok
 }
 
+// Unmarshaler is the interface implemented by types that can unmarshal
+// a bencoded value of themselves. The input is a single valid bencoded
+// value. UnmarshalBencode must copy the data if it wishes to retain it after
+// returning.
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
 // Unmarshal parses the bencoded data and stores the result in the value
 // pointed by v. If v is nil or not a pointer, Unmarshal returns an
-// ErrInvalidArgument.
+// ErrInvalidArgument. Data following the first value is ignored.
+//
+// Values are decoded straight from the data into the destination: dict keys
+// which match no struct field are skipped without being decoded, and a Value
+// tree is only built for Value destinations. Destinations of
+// type Value, *Dict, List, Int and String receive the parsed node itself,
+// with the order of dict keys and the bytes of strings preserved. Keys which
+// match no struct field are collected in the field tagged with the "rest"
+// option, if the struct has one. Number and big.Int destinations receive
+// integers of any size, and time.Time destinations Unix seconds, or the unit
+// of the time options described in Marshal. Types implementing
+// encoding.BinaryUnmarshaler or encoding.TextUnmarshaler are decoded from
+// strings, and may be map keys.
+//
+// Into an interface{}, integers are stored as int64, strings as string,
+// lists as []interface{} and dicts as map[string]interface{}. A Decoder can
+// be configured to store other types with UseBytes, UseDict, UseBigInt and
+// UseNumber.
 func Unmarshal(data []byte, i interface{}) error {
-	r := bytes.NewReader(data)
-	d := NewDecoder(r)
-	return d.Decode(i)
+	_, err := UnmarshalPrefix(data, i)
+	return err
 }
 
+// UnmarshalPrefix parses the first bencoded value of data, stores it in the
+// value pointed by i and returns the data following the value. It is useful
+// for messages in which a bencoded value is followed by raw bytes.
+func UnmarshalPrefix(data []byte, i interface{}) (rest []byte, err error) {
+	p := reflect.ValueOf(i)
+	if p.Kind() != reflect.Ptr || p.IsNil() {
+		return nil, &ErrInvalidArgument{reflect.TypeOf(i)}
+	}
+	d := &Decoder{}
+	s := NewScanner(data)
+	if err := d.decode(s, p.Elem()); err != nil {
+		return nil, err
+	}
+	return data[s.Offset():], nil
+}
+
+// Decoder reads bencoded values from an input stream.
 type Decoder struct {
-	reader io.Reader
-	parser *Parser
+	reader *bufio.Reader
+	offset int64
+
+	// options of decoding into interface{}
+	useBytes  bool
+	useDict   bool
+	useBigInt bool
+	useNumber bool
 }
 
+// NewDecoder returns a new decoder that reads from r. The decoder buffers
+// its input and may read past the values it decodes.
 func NewDecoder(r io.Reader) *Decoder {
-	p := NewParser(r)
-	return &Decoder{reader: r, parser: p}
+	return &Decoder{reader: bufio.NewReader(r)}
 }
 
-// Decode takes reader
+// UseBytes makes the decoder store strings as Bytes instead of string when
+// decoding into interface{}. Unlike []byte, Bytes is encoded back as
+// a string by Marshal.
+func (d *Decoder) UseBytes(enable bool) {
+	d.useBytes = enable
+}
+
+// UseDict makes the decoder store dicts as *Dict instead of
+// map[string]interface{} when decoding into interface{}, which keeps the
+// order of the keys and the exact bytes of the dicts. The values of a *Dict
+// are Value nodes, to which the other options do not apply.
+func (d *Decoder) UseDict(enable bool) {
+	d.useDict = enable
+}
+
+// UseBigInt makes the decoder store integers which do not fit into int64 as
+// *big.Int when decoding into interface{}, instead of failing.
+func (d *Decoder) UseBigInt(enable bool) {
+	d.useBigInt = enable
+}
+
+// UseNumber makes the decoder store all integers as Number instead of int64
+// when decoding into interface{}. It takes precedence over UseBigInt.
+func (d *Decoder) UseNumber(enable bool) {
+	d.useNumber = enable
+}
+
+// UnmarshalValue stores the already parsed value v in the value pointed by i.
+// If i is nil or not a pointer, UnmarshalValue returns an ErrInvalidArgument.
+func UnmarshalValue(v Value, i interface{}) error {
+	p := reflect.ValueOf(i)
+	if p.Kind() != reflect.Ptr || p.IsNil() {
+		return &ErrInvalidArgument{reflect.TypeOf(i)}
+	}
+	d := &Decoder{}
+	return d.decode(NewScanner(rawBytes(v)), p.Elem())
+}
+
+// Decode reads the next bencoded value from the stream and stores it in the
+// value pointed by i. At the end of the stream Decode returns io.EOF.
 func (d *Decoder) Decode(i interface{}) error {
 	// i must be a pointer
 	p := reflect.ValueOf(i)
@@ -50,96 +143,291 @@ func (d *Decoder) Decode(i interface{}) error {
 		return &ErrInvalidArgument{reflect.TypeOf(i)}
 	}
 
-	// parse data
-	v, err := d.parser.Parse()
+	start := d.offset
+	data, err := d.readValue()
 	if err != nil {
 		return err
 	}
 
-	rv := p.Elem() // get what p points to
-	return d.put(rv, v)
+	err = d.decode(NewScanner(data), p.Elem())
+	if e, ok := err.(*ErrSyntax); ok {
+		e.pos += start
+	}
+	return err
+}
+
+// maxChunk limits the memory allocated ahead of the data actually read for
+// a string of a claimed length.
+const maxChunk = 64 << 10
+
+// readValue reads the bytes of exactly one bencoded value from the stream.
+// It checks the structure of the value; integers and dict keys are checked
+// when the value is decoded.
+func (d *Decoder) readValue() ([]byte, error) {
+	var buf []byte
+	depth := 0
+	for {
+		c, err := d.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(buf) == 0 {
+				return nil, io.EOF
+			}
+			return nil, d.syntaxError(buf, "unexpected end of data")
+		}
+		buf = append(buf, c)
+
+		switch {
+		case c == 'i':
+			b, err := d.reader.ReadBytes('e')
+			buf = append(buf, b...)
+			if err != nil {
+				return nil, d.syntaxError(buf, "cannot find the end delimeter of the integer")
+			}
+		case c >= '0' && c <= '9':
+			b, err := d.reader.ReadBytes(':')
+			buf = append(buf, b...)
+			if err != nil {
+				return nil, d.syntaxError(buf, "cannot find string length delimeter")
+			}
+			n, err := strconv.ParseUint(string(buf[len(buf)-len(b)-1:len(buf)-1]), 10, 63)
+			if err != nil {
+				return nil, d.syntaxError(buf, "invalid string length")
+			}
+			for n > 0 {
+				chunk := int(n)
+				if n > maxChunk {
+					chunk = maxChunk
+				}
+				l := len(buf)
+				buf = append(buf, make([]byte, chunk)...)
+				if _, err := io.ReadFull(d.reader, buf[l:]); err != nil {
+					return nil, d.syntaxError(buf[:l], "string length is wrong")
+				}
+				n -= uint64(chunk)
+			}
+		case c == 'l' || c == 'd':
+			depth++
+			continue
+		case c == 'e' && depth > 0:
+			depth--
+		default:
+			return nil, d.syntaxError(buf[:len(buf)-1], "unexpected token")
+		}
+
+		if depth == 0 {
+			d.offset += int64(len(buf))
+			return buf, nil
+		}
+	}
+}
+
+// syntaxError returns an ErrSyntax at the end of buf, the bytes of the
+// current value read so far.
+func (d *Decoder) syntaxError(buf []byte, msg string) error {
+	return &ErrSyntax{pos: d.offset + int64(len(buf)), msg: msg}
 }
 
-// put dispatches putting data from Value to reflect.Value depending on
-// the Kind of the destination
-func (d *Decoder) put(dst reflect.Value, src Value) error {
-	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
-		dst.Set(reflect.ValueOf(src.Interface()))
+// decode dispatches decoding the next value of s into dst depending on the
+// Kind of the destination.
+func (d *Decoder) decode(s *Scanner, dst reflect.Value) error {
+	if dst.Kind() != reflect.Ptr && dst.CanAddr() && dst.Addr().Type().Implements(unmarshalerType) {
+		raw, err := s.ReadRaw()
+		if err != nil {
+			return err
+		}
+		return dst.Addr().Interface().(Unmarshaler).UnmarshalBencode(raw)
+	}
+	switch dst.Type() {
+	case valueType, dictPtrType, listType, intType, stringType:
+		return d.decodeValue(s, dst)
+	case numberType, bigIntType:
+		return d.decodeNumber(s, dst)
+	case timeType:
+		return d.decodeTime(s, dst, 0)
+	}
+	if dst.Kind() != reflect.Ptr && dst.CanAddr() && isTextUnmarshaler(dst.Addr().Type()) {
+		if c := s.Peek(); c < '0' || c > '9' {
+			return mismatch(s, dst.Type().String())
+		}
+		b, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		return unmarshalText(dst.Addr(), b)
 	}
 
 	switch dst.Kind() {
-	case reflect.Int64:
-		return d.putInt(dst, src)
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			v, err := d.decodeInterface(s)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(v))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return d.decodeInt(s, dst)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return d.decodeUint(s, dst)
 	case reflect.String:
-		return d.putString(dst, src)
+		return d.decodeString(s, dst)
 	case reflect.Slice:
 		if dst.Type().Elem().Kind() == reflect.Uint8 {
-			return d.putBencode(dst, src)
+			return d.decodeBencode(s, dst)
 		}
-		return d.putSlice(dst, src)
+		return d.decodeSlice(s, dst)
+	case reflect.Array:
+		return d.decodeArray(s, dst)
 	case reflect.Map:
-		return d.putMap(dst, src)
+		return d.decodeMap(s, dst)
 	case reflect.Struct:
-		return d.putStruct(dst, src)
+		return d.decodeStruct(s, dst)
+	case reflect.Ptr:
+		// handles allocating a new value if dst is a nil pointer
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.decode(s, dst.Elem())
 	}
 
+	return &ErrUnsupportedType{dst.Type()}
+}
+
+// mismatch returns the error for the next value of s not fitting into
+// a destination of the given kind.
+func mismatch(s *Scanner, kind string) error {
+	var t string
+	switch c := s.Peek(); {
+	case c == 'i':
+		t = "bencode.Int"
+	case c == 'l':
+		t = "bencode.List"
+	case c == 'd':
+		t = "*bencode.Dict"
+	case c >= '0' && c <= '9':
+		t = "bencode.String"
+	default:
+		// let the scanner report the syntax error
+		return s.Skip()
+	}
+	return fmt.Errorf("trying to put %s into %s", t, kind)
+}
+
+func (d *Decoder) decodeInt(s *Scanner, dst reflect.Value) error {
+	if s.Peek() != 'i' {
+		return mismatch(s, "int")
+	}
+	i, err := s.ReadInt()
+	if err != nil {
+		return err
+	}
+
+	if dst.OverflowInt(i) {
+		return fmt.Errorf("%d overflows %s", i, dst.Type())
+	}
+	dst.SetInt(i)
+
 	return nil
 }
 
-func (d *Decoder) putInt(dst reflect.Value, src Value) error {
-	i, ok := src.(Int)
-	if !ok {
-		return fmt.Errorf("trying to put %T into int", src)
+func (d *Decoder) decodeUint(s *Scanner, dst reflect.Value) error {
+	if s.Peek() != 'i' {
+		return mismatch(s, "uint")
+	}
+	i, err := s.ReadInt()
+	if err != nil {
+		return err
 	}
 
-	dst.SetInt(int64(i))
+	if i < 0 || dst.OverflowUint(uint64(i)) {
+		return fmt.Errorf("%d overflows %s", i, dst.Type())
+	}
+	dst.SetUint(uint64(i))
 
 	return nil
 }
 
-func (d *Decoder) putString(dst reflect.Value, src Value) error {
-	s, ok := src.(String)
-	if !ok {
-		return fmt.Errorf("trying to put %T into string", src)
+func (d *Decoder) decodeString(s *Scanner, dst reflect.Value) error {
+	if c := s.Peek(); c < '0' || c > '9' {
+		return mismatch(s, "string")
+	}
+	b, err := s.ReadString()
+	if err != nil {
+		return err
 	}
 
-	dst.SetString(string(s))
+	dst.SetString(string(b))
 
 	return nil
 }
 
-func (d *Decoder) putSlice(dst reflect.Value, src Value) error {
-	l, ok := src.(List)
-	if !ok {
-		return fmt.Errorf("trying to put %T into slice", src)
+func (d *Decoder) decodeSlice(s *Scanner, dst reflect.Value) error {
+	if s.Peek() != 'l' {
+		return mismatch(s, "slice")
+	}
+	if err := s.ReadListStart(); err != nil {
+		return err
+	}
+
+	// elements beyond the length of the destination are appended, which
+	// also handles allocating a new slice if dst is a nil slice
+	elemType := dst.Type().Elem()
+	for i := 0; s.More(); i++ {
+		if i < dst.Len() {
+			if err := d.decode(s, dst.Index(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		elem := reflect.New(elemType).Elem()
+		if err := d.decode(s, elem); err != nil {
+			return err
+		}
+		dst.Set(reflect.Append(dst, elem))
 	}
 
-	// extend destination slice, if needed
-	// also handles allocating new slice if dst is nil slice
-	dif := len(l) - dst.Len()
-	if dif > 0 {
-		ext := reflect.MakeSlice(dst.Type(), dif, dif)
-		dst.Set(reflect.AppendSlice(dst, ext))
+	return s.ReadEnd()
+}
+
+func (d *Decoder) decodeArray(s *Scanner, dst reflect.Value) error {
+	if s.Peek() != 'l' {
+		return mismatch(s, "array")
+	}
+	if err := s.ReadListStart(); err != nil {
+		return err
 	}
 
-	for i, v := range l {
-		elem := dst.Index(i)
-		d.put(elem, v)
+	i := 0
+	for ; s.More(); i++ {
+		if i >= dst.Len() {
+			return fmt.Errorf("list overflows %s", dst.Type())
+		}
+		if err := d.decode(s, dst.Index(i)); err != nil {
+			return err
+		}
+	}
+	// zero the elements the list does not cover
+	for ; i < dst.Len(); i++ {
+		dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
 	}
 
-	return nil
+	return s.ReadEnd()
 }
 
-func (d *Decoder) putMap(dst reflect.Value, src Value) error {
+func (d *Decoder) decodeMap(s *Scanner, dst reflect.Value) error {
 	// only strings allowed to be the keys in bencode
 	mapKeyType := dst.Type().Key()
-	if mapKeyType.Kind() != reflect.String {
-		return fmt.Errorf("map keys must be of type string, not %v", mapKeyType)
+	textKey := mapKeyType.Kind() != reflect.String
+	if textKey && !isTextUnmarshaler(reflect.PtrTo(mapKeyType)) {
+		return fmt.Errorf("map keys must be strings or implement encoding.TextUnmarshaler or encoding.BinaryUnmarshaler, not %v", mapKeyType)
 	}
 
-	dict, ok := src.(*Dict)
-	if !ok {
-		return fmt.Errorf("trying to put %T into map", src)
+	if s.Peek() != 'd' {
+		return mismatch(s, "map")
+	}
+	if err := s.ReadDictStart(); err != nil {
+		return err
 	}
 
 	// handles allocating a new map if dst is a nil map (zero value)
@@ -148,38 +436,356 @@ func (d *Decoder) putMap(dst reflect.Value, src Value) error {
 	}
 
 	mapElemType := dst.Type().Elem()
-	for k, v := range dict.m {
-		key := reflect.ValueOf(string(k))
+	for s.More() {
+		k, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		var key reflect.Value
+		if textKey {
+			key = reflect.New(mapKeyType)
+			if err := unmarshalText(key, k); err != nil {
+				return err
+			}
+			key = key.Elem()
+		} else {
+			key = reflect.ValueOf(string(k)).Convert(mapKeyType)
+		}
 		elem := reflect.New(mapElemType).Elem()
-		d.put(elem, v)
+		if err := d.decode(s, elem); err != nil {
+			return err
+		}
 		dst.SetMapIndex(key, elem)
 	}
 
+	return s.ReadEnd()
+}
+
+func (d *Decoder) decodeStruct(s *Scanner, dst reflect.Value) error {
+	fields, err := cachedFields(dst.Type())
+	if err != nil {
+		return err
+	}
+
+	if s.Peek() != 'd' {
+		return mismatch(s, "struct")
+	}
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		// the conversion in a map index does not allocate
+		i, ok := fields.index[string(key)]
+		switch {
+		case ok && fields.units[i] != 0:
+			err = d.decodeTime(s, dst.Field(i), fields.units[i])
+		case ok:
+			err = d.decode(s, dst.Field(i))
+		case fields.rest >= 0:
+			err = d.decodeRest(s, dst.Field(fields.rest), key)
+		default:
+			err = s.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.ReadEnd()
+}
+
+// decodeRest adds the next value of s under key to the rest field dst.
+func (d *Decoder) decodeRest(s *Scanner, dst reflect.Value, key []byte) error {
+	if dst.Type() == dictPtrType {
+		v, err := scanValue(s)
+		if err != nil {
+			return err
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.ValueOf(NewDict()))
+		}
+		dst.Interface().(*Dict).Set(String(key), v)
+		return nil
+	}
+
+	raw, err := s.ReadRaw()
+	if err != nil {
+		return err
+	}
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMap(dst.Type()))
+	}
+	k := reflect.ValueOf(string(key)).Convert(dst.Type().Key())
+	v := reflect.ValueOf(append([]byte(nil), raw...)).Convert(dst.Type().Elem())
+	dst.SetMapIndex(k, v)
+	return nil
+}
+
+var (
+	dictPtrType = reflect.TypeOf((*Dict)(nil))
+	listType    = reflect.TypeOf(List(nil))
+	intType     = reflect.TypeOf(Int(0))
+	stringType  = reflect.TypeOf(String(""))
+)
+
+// decodeValue stores the parsed node in a destination of type Value, *Dict,
+// List, Int or String.
+func (d *Decoder) decodeValue(s *Scanner, dst reflect.Value) error {
+	v, err := scanValue(s)
+	if err != nil {
+		return err
+	}
+	src := reflect.ValueOf(v)
+	if !src.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("trying to put %T into %s", v, dst.Type())
+	}
+	dst.Set(src)
 	return nil
 }
 
-func (d *Decoder) putStruct(dst reflect.Value, src Value) error {
-	dict, ok := src.(*Dict)
-	if !ok {
-		return fmt.Errorf("trying to put %T into struct", src)
+// decodeNumber stores an integer of any size in a Number or big.Int.
+func (d *Decoder) decodeNumber(s *Scanner, dst reflect.Value) error {
+	if s.Peek() != 'i' {
+		return mismatch(s, "int")
 	}
+	b, err := s.readNumber()
+	if err != nil {
+		return err
+	}
+	if dst.Type() == numberType {
+		dst.SetString(string(b))
+		return nil
+	}
+	n, _ := new(big.Int).SetString(string(b), 10)
+	dst.Set(reflect.ValueOf(n).Elem())
+	return nil
+}
 
-	for i := 0; i < dst.NumField(); i++ {
-		field := dst.Field(i)
-		if !field.CanSet() {
-			return fmt.Errorf("struct field must be settable, i.e. exported")
+// decodeTime stores an integer in a time.Time or time.Duration, or in
+// a pointer to one, in the unit.
+func (d *Decoder) decodeTime(s *Scanner, dst reflect.Value, unit time.Duration) error {
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
 		}
-		tag := dst.Type().Field(i).Tag.Get("bencode")
-		if value := dict.Get(String(tag)); value != nil {
-			if err := d.put(field, value); err != nil {
-				return err
-			}
+		dst = dst.Elem()
+	}
+	if s.Peek() != 'i' {
+		return mismatch(s, dst.Type().String())
+	}
+	n, err := s.ReadInt()
+	if err != nil {
+		return err
+	}
+
+	if dst.Type() == timeType {
+		t, err := decodeTime(n, unit)
+		if err != nil {
+			return err
 		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	v, err := decodeDuration(n, unit)
+	if err != nil {
+		return err
 	}
+	dst.SetInt(int64(v))
 	return nil
 }
 
-func (d *Decoder) putBencode(dst reflect.Value, src Value) error {
-	dst.Set(reflect.ValueOf(src.Bencode()))
+// decodeInterface returns the next value of s as stored in an empty
+// interface, depending on the options of d.
+func (d *Decoder) decodeInterface(s *Scanner) (interface{}, error) {
+	switch c := s.Peek(); {
+	case c == 'i':
+		if !d.useNumber && !d.useBigInt {
+			return s.ReadInt()
+		}
+		b, err := s.readNumber()
+		if err != nil {
+			return nil, err
+		}
+		if d.useNumber {
+			return Number(b), nil
+		}
+		if i, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return i, nil
+		}
+		n, _ := new(big.Int).SetString(string(b), 10)
+		return n, nil
+	case c == 'l':
+		list := []interface{}{}
+		if err := s.ReadListStart(); err != nil {
+			return nil, err
+		}
+		for s.More() {
+			v, err := d.decodeInterface(s)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, s.ReadEnd()
+	case c == 'd':
+		if d.useDict {
+			return scanValue(s)
+		}
+		m := make(map[string]interface{})
+		if err := s.ReadDictStart(); err != nil {
+			return nil, err
+		}
+		for s.More() {
+			k, err := s.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decodeInterface(s)
+			if err != nil {
+				return nil, err
+			}
+			m[string(k)] = v
+		}
+		return m, s.ReadEnd()
+	}
+
+	b, err := s.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	if d.useBytes {
+		return append(Bytes{}, b...), nil
+	}
+	return string(b), nil
+}
+
+func (d *Decoder) decodeBencode(s *Scanner, dst reflect.Value) error {
+	raw, err := s.ReadRaw()
+	if err != nil {
+		return err
+	}
+	dst.SetBytes(append([]byte(nil), raw...))
 	return nil
 }
+
+// structFields maps the dict keys of a struct type to its field indices.
+// rest is the index of the field tagged with the "rest" option, or -1, and
+// units holds the time units of fields by index.
+type structFields struct {
+	index map[string]int
+	rest  int
+	units map[int]time.Duration
+	err   error
+}
+
+var fieldCache sync.Map // map[reflect.Type]structFields
+
+// cachedFields returns the field indices of the struct type t.
+func cachedFields(t reflect.Type) (structFields, error) {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(structFields), f.(structFields).err
+	}
+
+	f := structFields{index: make(map[string]int), rest: -1}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, opts, ok := fieldKey(field)
+		if !ok {
+			continue
+		}
+		if field.PkgPath != "" {
+			f.err = fmt.Errorf("struct field must be settable, i.e. exported")
+			break
+		}
+		if opts.Contains("rest") {
+			if f.err = checkRest(field, f.rest >= 0); f.err != nil {
+				break
+			}
+			f.rest = i
+			continue
+		}
+		unit, err := timeUnit(field, opts)
+		if err != nil {
+			f.err = err
+			break
+		}
+		if unit != 0 {
+			if f.units == nil {
+				f.units = make(map[int]time.Duration)
+			}
+			f.units[i] = unit
+		}
+		if _, dup := f.index[key]; !dup {
+			f.index[key] = i
+		}
+	}
+
+	fieldCache.Store(t, f)
+	return f, f.err
+}
+
+// scanValue reads the next value of s into a Value tree. Parsed dicts keep
+// their exact bytes as Dict.Raw, in a copy of the data.
+func scanValue(s *Scanner) (Value, error) {
+	raw, err := s.ReadRaw()
+	if err != nil {
+		return nil, err
+	}
+	return scanTree(NewScanner(append([]byte(nil), raw...)))
+}
+
+// scanTree builds a Value tree from valid data owned by the tree.
+func scanTree(s *Scanner) (Value, error) {
+	switch c := s.Peek(); {
+	case c == 'i':
+		i, err := s.ReadInt()
+		return Int(i), err
+	case c == 'l':
+		list := List{}
+		s.ReadListStart()
+		for s.More() {
+			v, err := scanTree(s)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, s.ReadEnd()
+	case c == 'd':
+		start := s.Offset()
+		dict := NewDict()
+		s.ReadDictStart()
+		for s.More() {
+			key, err := s.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			v, err := scanTree(s)
+			if err != nil {
+				return nil, err
+			}
+			dict.Set(String(key), v)
+		}
+		if err := s.ReadEnd(); err != nil {
+			return nil, err
+		}
+		dict.raw = s.data[start:s.pos:s.pos]
+		return dict, nil
+	}
+	b, err := s.ReadString()
+	return String(b), err
+}
+
+// rawBytes returns the bencoded src, preferring the exact bytes it was
+// parsed from.
+func rawBytes(src Value) []byte {
+	if dict, ok := src.(*Dict); ok && dict.Raw() != nil {
+		return append([]byte(nil), dict.Raw()...)
+	}
+	return src.Bencode()
+}
@@ -2,7 +2,10 @@ package bencode
 
 import (
 	"errors"
+	"io"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -65,6 +68,38 @@ func TestUnmarshalIntoInt(t *testing.T) {
 	})
 }
 
+func TestUnmarshalIntoSizedInt(t *testing.T) {
+	t.Run("Int8", func(t *testing.T) {
+		var got int8
+		err := Unmarshal([]byte(`i-128e`), &got)
+
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if got != -128 {
+			t.Errorf("\ngot: %v \nwant: %v", got, -128)
+		}
+	})
+
+	t.Run("Int8 overflow", func(t *testing.T) {
+		var got int8
+		err := Unmarshal([]byte(`i128e`), &got)
+
+		if err == nil {
+			t.Error("no error, got:", got)
+		}
+	})
+
+	t.Run("Uint negative", func(t *testing.T) {
+		var got uint
+		err := Unmarshal([]byte(`i-1e`), &got)
+
+		if err == nil {
+			t.Error("no error, got:", got)
+		}
+	})
+}
+
 func TestUnmarshalIntoString(t *testing.T) {
 	t.Run("Simple string", func(t *testing.T) {
 		input := `4:spam`
@@ -133,6 +168,24 @@ func TestUnmarshalIntoMap(t *testing.T) {
 	})
 }
 
+func TestUnmarshalIntoNestedMap(t *testing.T) {
+	t.Run("Empty keys", func(t *testing.T) {
+		input := `d4:filed0:d6:lengthi1eeee`
+		want := map[string]map[string]map[string]int64{"file": {"": {"length": 1}}}
+
+		var got map[string]map[string]map[string]int64
+		data := []byte(input)
+		err := Unmarshal(data, &got)
+
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot: %v \nwant: %v", got, want)
+		}
+	})
+}
+
 func TestUnmarshalIntoStruct(t *testing.T) {
 	t.Run("Exported error", func(t *testing.T) {
 		type TestStruct struct {
@@ -184,6 +237,26 @@ func TestUnmarshalIntoStruct(t *testing.T) {
 		}
 	})
 
+	t.Run("Pointer and untagged fields", func(t *testing.T) {
+		type TestStruct struct {
+			A       *int64 `bencode:"a,omitempty"`
+			Name    string
+			Skipped string `bencode:"-"`
+		}
+		input := `d1:ai1e4:Name4:spam1:-4:eggse`
+
+		got := &TestStruct{}
+		data := []byte(input)
+		err := Unmarshal(data, got)
+
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if got.A == nil || *got.A != 1 || got.Name != "spam" || got.Skipped != "" {
+			t.Errorf("\ngot: %+v", got)
+		}
+	})
+
 	t.Run("Raw bencoded field", func(t *testing.T) {
 		type TestStruct struct {
 			Raw []byte `bencode:"raw"`
@@ -205,3 +278,460 @@ func TestUnmarshalIntoStruct(t *testing.T) {
 		}
 	})
 }
+
+func TestUnmarshalPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+		rest  string
+	}{
+		{"Int", "i42e\x00\x01", int64(42), "\x00\x01"},
+		{"String", "4:spam4:eggs", "spam", "4:eggs"},
+		{"Dict", "d1:ai1ee\xff\xfe", map[string]interface{}{"a": int64(1)}, "\xff\xfe"},
+		{"No rest", "le", []interface{}{}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got interface{}
+			rest, err := UnmarshalPrefix([]byte(test.input), &got)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %v \nwant: %v", got, test.want)
+			}
+			if string(rest) != test.rest {
+				t.Errorf("\ngot: %q \nwant: %q", rest, test.rest)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSkipsUnknownKeys(t *testing.T) {
+	type torrent struct {
+		Announce string `bencode:"announce"`
+		Length   int64  `bencode:"length"`
+	}
+	data := []byte("d8:announce3:url7:comment8:ignored!6:lengthi42e5:nodesll3:abci1eeee")
+
+	var got torrent
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if want := (torrent{"url", 42}); got != want {
+		t.Error("got:", got, "want:", want)
+	}
+
+	var length struct {
+		Length int64 `bencode:"length"`
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		Unmarshal(data, &length)
+	})
+	if allocs > 2 {
+		t.Error("got:", allocs, "allocations, want: at most 2")
+	}
+}
+
+func TestUnmarshalReuseSlice(t *testing.T) {
+	got := []string{"x", "y", "z"}
+	if err := Unmarshal([]byte("l1:ae"), &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if want := []string{"a", "y", "z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %v \nwant: %v", got, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		dst   interface{}
+	}{
+		{"Empty", "", new(int)},
+		{"Type", "4:spam", new(int)},
+		{"Truncated", "d1:ai1e", new(map[string]int)},
+		{"Truncated skip", "d1:bli1e", new(struct{ A int })},
+		{"Key", "di1ei1ee", new(map[string]int)},
+		{"Unexported", "de", new(struct{ a int })},
+		{"Overflow", "i256e", new(uint8)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Unmarshal([]byte(test.input), test.dst); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
+
+func TestDecoderStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("i1e4:spamd1:ai2eel1:be"))
+
+	var i int
+	var s string
+	var m map[string]int
+	var l []string
+	for _, v := range []interface{}{&i, &s, &m, &l} {
+		if err := d.Decode(v); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if i != 1 || s != "spam" || m["a"] != 2 || len(l) != 1 || l[0] != "b" {
+		t.Error("got:", i, s, m, l)
+	}
+	if err := d.Decode(&i); err != io.EOF {
+		t.Error("got:", err, "want:", io.EOF)
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int64
+	}{
+		{"i1ed1:a", 7},
+		{"i1el4:spe", 6},
+		{"i1ex", 3},
+		{"4:spami1", 8},
+		{"led1:a", 6},
+	}
+
+	for _, test := range tests {
+		d := NewDecoder(strings.NewReader(test.input))
+		var v interface{}
+		err := d.Decode(&v)
+		for err == nil {
+			err = d.Decode(&v)
+		}
+		var e *ErrSyntax
+		if !errors.As(err, &e) {
+			t.Errorf("%q: got: %v want: a syntax error", test.input, err)
+		} else if e.pos != test.pos {
+			t.Errorf("%q: got: %d want: %d", test.input, e.pos, test.pos)
+		}
+	}
+}
+
+func TestUnmarshalIntoValue(t *testing.T) {
+	type resume struct {
+		Version int64   `bencode:"version"`
+		Extra   *Dict   `bencode:"extra,omitempty"`
+		Any     Value   `bencode:"any,omitempty"`
+		Peers   List    `bencode:"peers,omitempty"`
+		Name    String  `bencode:"name,omitempty"`
+		Values  []Value `bencode:"values,omitempty"`
+	}
+	input := "d3:anyli1ee5:extrad1:z1:\xff1:ai1ee4:name4:spam5:peersl1:ae6:valuesli1e1:be7:versioni2ee"
+
+	var got resume
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := resume{
+		Version: 2,
+		Extra:   NewDict(DictItem{"z", String("\xff")}, DictItem{"a", Int(1)}),
+		Any:     List{Int(1)},
+		Peers:   List{String("a")},
+		Name:    "spam",
+		Values:  []Value{Int(1), String("b")},
+	}
+	if got.Version != want.Version || got.Name != want.Name ||
+		!reflect.DeepEqual(got.Any, want.Any) || !reflect.DeepEqual(got.Peers, want.Peers) ||
+		!reflect.DeepEqual(got.Values, want.Values) {
+		t.Errorf("\ngot: %v \nwant: %v", got, want)
+	}
+	if string(got.Extra.Raw()) != "d1:z1:\xff1:ai1ee" {
+		t.Errorf("\ngot: %q \nwant: %q", got.Extra.Raw(), "d1:z1:\xff1:ai1ee")
+	}
+	if keys := got.Extra.Keys(); len(keys) != 2 || keys[0] != "z" || keys[1] != "a" {
+		t.Error("got:", keys, "want: [z a]")
+	}
+
+	data, err := Marshal(got)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(data) != input {
+		t.Errorf("\ngot: %q \nwant: %q", data, input)
+	}
+
+	var v Value
+	if err := Unmarshal([]byte("d1:bi1e1:ai2ee"), &v); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if d, ok := v.(*Dict); !ok || string(d.Bencode()) != "d1:bi1e1:ai2ee" {
+		t.Errorf("got: %#v", v)
+	}
+
+	for input, dst := range map[string]interface{}{
+		"i1e":   new(*Dict),
+		"de":    new(List),
+		"le":    new(Int),
+		"l1:ae": new(String),
+	} {
+		if err := Unmarshal([]byte(input), dst); err == nil {
+			t.Error("no error for", input)
+		}
+	}
+}
+
+func TestRestField(t *testing.T) {
+	input := "d1:ai1e1:bl1:xe1:ci3e1:d1:ye"
+
+	t.Run("Map", func(t *testing.T) {
+		var got struct {
+			A    int                   `bencode:"a"`
+			C    int                   `bencode:"c"`
+			Rest map[string]RawMessage `bencode:",rest"`
+		}
+		if err := Unmarshal([]byte(input), &got); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		want := map[string]RawMessage{"b": RawMessage("l1:xe"), "d": RawMessage("1:y")}
+		if got.A != 1 || got.C != 3 || !reflect.DeepEqual(got.Rest, want) {
+			t.Errorf("\ngot: %v \nwant: %v", got.Rest, want)
+		}
+
+		// a key of a field is not written twice
+		got.Rest["a"] = RawMessage("i9e")
+		data, err := Marshal(got)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if string(data) != input {
+			t.Errorf("\ngot: %q \nwant: %q", data, input)
+		}
+	})
+
+	t.Run("Dict", func(t *testing.T) {
+		var got struct {
+			B    []string `bencode:"b"`
+			Rest *Dict    `bencode:",rest"`
+		}
+		if err := Unmarshal([]byte(input), &got); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if got.Rest == nil || string(got.Rest.Bencode()) != "d1:ai1e1:ci3e1:d1:ye" {
+			t.Errorf("got: %v", got.Rest)
+		}
+
+		data, err := Marshal(got)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if string(data) != input {
+			t.Errorf("\ngot: %q \nwant: %q", data, input)
+		}
+	})
+
+	t.Run("No unknown keys", func(t *testing.T) {
+		var got struct {
+			A    int                   `bencode:"a"`
+			Rest map[string]RawMessage `bencode:",rest"`
+		}
+		if err := Unmarshal([]byte("d1:ai1ee"), &got); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if got.Rest != nil {
+			t.Error("got:", got.Rest, "want: nil")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		type wrongType struct {
+			Rest map[string]string `bencode:",rest"`
+		}
+		type twoFields struct {
+			Rest  *Dict `bencode:",rest"`
+			Other *Dict `bencode:",rest"`
+		}
+		for _, v := range []interface{}{&wrongType{}, &twoFields{}} {
+			if err := Unmarshal([]byte(input), v); err == nil {
+				t.Errorf("no error for %T", v)
+			}
+			if _, err := Marshal(v); err == nil {
+				t.Errorf("no error for %T", v)
+			}
+		}
+	})
+}
+
+func TestDecoderOptions(t *testing.T) {
+	big1, _ := new(big.Int).SetString("18446744073709551616", 10)
+	input := "d1:bi18446744073709551616e1:a2:\xff\x00e"
+
+	tests := []struct {
+		name string
+		set  func(d *Decoder)
+		want interface{}
+	}{
+		{"Bytes", func(d *Decoder) { d.UseBytes(true); d.UseBigInt(true) },
+			map[string]interface{}{"a": Bytes("\xff\x00"), "b": big1}},
+		{"Number", func(d *Decoder) { d.UseNumber(true); d.UseBigInt(true) },
+			map[string]interface{}{"a": "\xff\x00", "b": Number("18446744073709551616")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(input))
+			test.set(d)
+
+			var got interface{}
+			if err := d.Decode(&got); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %#v \nwant: %#v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("Dict", func(t *testing.T) {
+		input := "ld1:bi0e1:a2:\xff\x00ee"
+		d := NewDecoder(strings.NewReader(input))
+		d.UseDict(true)
+
+		var got interface{}
+		if err := d.Decode(&got); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		l, ok := got.([]interface{})
+		if !ok || len(l) != 1 {
+			t.Fatalf("got: %#v", got)
+		}
+		if dict, ok := l[0].(*Dict); !ok || string(dict.Bencode()) != input[1:len(input)-1] {
+			t.Errorf("got: %#v", l[0])
+		}
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		for _, input := range []string{"l3:i1ee", "l4:spame", "d1:a0:1:bld1:c2:\xff\x00eee"} {
+			d := NewDecoder(strings.NewReader(input))
+			d.UseBytes(true)
+			d.UseNumber(true)
+
+			var v interface{}
+			if err := d.Decode(&v); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			got, err := Marshal(v)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(got) != input {
+				t.Errorf("\ngot: %q \nwant: %q", got, input)
+			}
+		}
+	})
+
+	t.Run("Small ints", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader("li1ei-2ee"))
+		d.UseBigInt(true)
+		var got interface{}
+		if err := d.Decode(&got); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if want := []interface{}{int64(1), int64(-2)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot: %#v \nwant: %#v", got, want)
+		}
+	})
+
+	t.Run("Without options", func(t *testing.T) {
+		var got interface{}
+		if err := Unmarshal([]byte(input), &got); err == nil {
+			t.Error("no error for", input)
+		}
+	})
+}
+
+func TestNumber(t *testing.T) {
+	var got struct {
+		N Number   `bencode:"n"`
+		B *big.Int `bencode:"b"`
+	}
+	input := "d1:bi-18446744073709551616e1:ni36893488147419103232ee"
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if got.N != "36893488147419103232" || got.B.String() != "-18446744073709551616" {
+		t.Error("got:", got.N, got.B)
+	}
+	if _, err := got.N.Int64(); err == nil {
+		t.Error("no error for", got.N)
+	}
+	if n, ok := got.N.BigInt(); !ok || n.String() != string(got.N) {
+		t.Error("got:", n, "want:", got.N)
+	}
+
+	data, err := Marshal(got)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(data) != input {
+		t.Errorf("\ngot: %q \nwant: %q", data, input)
+	}
+
+	for _, n := range []Number{"", "-", "1x", "1e"} {
+		if _, err := Marshal(n); err == nil {
+			t.Error("no error for", n)
+		}
+	}
+}
+
+func TestUnmarshalIntoArray(t *testing.T) {
+	type pair struct {
+		A [2]int `bencode:"a"`
+	}
+	data, err := Marshal(pair{[2]int{1, 2}})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var got pair
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if want := [2]int{1, 2}; got.A != want {
+		t.Error("got:", got.A, "want:", want)
+	}
+
+	// elements the list does not cover are zeroed
+	short := [3]string{"x", "y", "z"}
+	if err := Unmarshal([]byte("l1:ae"), &short); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if want := [3]string{"a", "", ""}; short != want {
+		t.Error("got:", short, "want:", want)
+	}
+
+	var long [1]int
+	if err := Unmarshal([]byte("li1ei2ee"), &long); err == nil {
+		t.Error("no error for", "li1ei2ee")
+	}
+}
+
+func TestUnmarshalUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		dst   interface{}
+	}{
+		{"Bool", "i1e", new(bool)},
+		{"Float", "i1e", new(float64)},
+		{"Interface", "i1e", new(error)},
+		{"Field", "d1:Fi1ee", new(struct{ F float32 })},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Unmarshal([]byte(test.input), test.dst)
+			if !errors.Is(err, &ErrUnsupportedType{}) {
+				t.Error("got:", err, "want: an ErrUnsupportedType")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package dht
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+)
+
+// MemNetwork is an in-memory packet network for tests. Its connections
+// implement net.PacketConn with *net.UDPAddr addresses, so a Node can run
+// on it in place of UDP sockets.
+type MemNetwork struct {
+	mu    sync.Mutex
+	conns map[netip.AddrPort]*memConn
+}
+
+// NewMemNetwork returns an empty in-memory network.
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{conns: make(map[netip.AddrPort]*memConn)}
+}
+
+// Listen returns a connection bound to addr.
+func (n *MemNetwork) Listen(addr netip.AddrPort) (net.PacketConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.conns[addr]; ok {
+		return nil, errors.New("dht: address already in use")
+	}
+	c := &memConn{
+		network: n,
+		addr:    addr,
+		packets: make(chan memPacket, 64),
+		closed:  make(chan struct{}),
+	}
+	n.conns[addr] = c
+	return c, nil
+}
+
+type memPacket struct {
+	data []byte
+	from netip.AddrPort
+}
+
+type memConn struct {
+	network *MemNetwork
+	addr    netip.AddrPort
+	packets chan memPacket
+	closed  chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (c *memConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case pkt := <-c.packets:
+		n := copy(p, pkt.data)
+		return n, net.UDPAddrFromAddrPort(pkt.from), nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	case <-timeout:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+// WriteTo delivers p to the connection bound to addr. Like UDP, packets to
+// unknown addresses or to full queues are silently dropped.
+func (c *memConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	to, ok := toAddrPort(addr)
+	if !ok {
+		return 0, errors.New("dht: unsupported address type")
+	}
+
+	c.network.mu.Lock()
+	dst := c.network.conns[to]
+	c.network.mu.Unlock()
+	if dst != nil {
+		pkt := memPacket{data: append([]byte(nil), p...), from: c.addr}
+		select {
+		case dst.packets <- pkt:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		c.network.mu.Lock()
+		delete(c.network.conns, c.addr)
+		c.network.mu.Unlock()
+	})
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr { return net.UDPAddrFromAddrPort(c.addr) }
+
+func (c *memConn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *memConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// toAddrPort converts an address of a packet connection.
+func toAddrPort(addr net.Addr) (netip.AddrPort, bool) {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ap := a.AddrPort()
+		return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), true
+	case interface{ AddrPort() netip.AddrPort }:
+		return a.AddrPort(), true
+	}
+	return netip.AddrPort{}, false
+}
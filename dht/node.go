@@ -0,0 +1,431 @@
+// Package dht implements a minimal DHT node (BEP 5) on top of the krpc
+// messages. It runs on any net.PacketConn, so tests can use MemNetwork in
+// place of UDP sockets.
+package dht
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ortymid/bencode/krpc"
+)
+
+// alpha is the number of concurrent queries of an iterative lookup.
+const alpha = 3
+
+// DefaultTimeout is how long a query waits for a response.
+const DefaultTimeout = 5 * time.Second
+
+// ErrTimeout is returned when a queried node does not respond in time.
+var ErrTimeout = errors.New("dht: query timed out")
+
+// Node is a DHT node. Create it with NewNode and run Serve to handle
+// incoming messages; queries need Serve to be running to get responses.
+type Node struct {
+	id      krpc.ID
+	conn    net.PacketConn
+	table   *Table
+	tokens  *tokens
+	timeout time.Duration
+
+	mu      sync.Mutex
+	nextT   uint16
+	pending map[string]chan *krpc.Message
+	peers   map[krpc.ID]map[netip.AddrPort]bool
+}
+
+// NewNode returns a node with the given ID which communicates over conn.
+func NewNode(conn net.PacketConn, id krpc.ID) *Node {
+	return &Node{
+		id:      id,
+		conn:    conn,
+		table:   NewTable(id),
+		tokens:  newTokens(),
+		timeout: DefaultTimeout,
+		pending: make(map[string]chan *krpc.Message),
+		peers:   make(map[krpc.ID]map[netip.AddrPort]bool),
+	}
+}
+
+// ID returns the ID of the node.
+func (n *Node) ID() krpc.ID { return n.id }
+
+// Table returns the routing table of the node.
+func (n *Node) Table() *Table { return n.table }
+
+// SetTimeout sets how long a query waits for a response.
+func (n *Node) SetTimeout(d time.Duration) { n.timeout = d }
+
+// Addr returns the address the node listens on.
+func (n *Node) Addr() netip.AddrPort {
+	addr, _ := toAddrPort(n.conn.LocalAddr())
+	return addr
+}
+
+// Serve reads and handles incoming messages until the connection is
+// closed. Malformed packets are ignored.
+func (n *Node) Serve() error {
+	buf := make([]byte, 64<<10)
+	for {
+		size, from, err := n.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		addr, ok := toAddrPort(from)
+		if !ok {
+			continue
+		}
+		msg, err := krpc.Unmarshal(buf[:size])
+		if err != nil {
+			continue
+		}
+		n.handle(msg, addr)
+	}
+}
+
+// Close closes the connection of the node, which stops Serve.
+func (n *Node) Close() error {
+	return n.conn.Close()
+}
+
+func (n *Node) handle(msg *krpc.Message, from netip.AddrPort) {
+	if msg.Y != krpc.TypeQuery {
+		n.mu.Lock()
+		ch, ok := n.pending[msg.T]
+		delete(n.pending, msg.T)
+		n.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+		return
+	}
+
+	var id krpc.ID
+	copy(id[:], msg.A.ID)
+	n.table.Add(krpc.NodeInfo{ID: id, Addr: from})
+
+	var reply *krpc.Message
+	switch msg.Q {
+	case krpc.MethodPing:
+		reply = krpc.NewResponse(msg.T, krpc.Return{ID: string(n.id[:])})
+	case krpc.MethodFindNode:
+		target, ok := toID(msg.A.Target)
+		if !ok {
+			reply = krpc.NewError(msg.T, krpc.ErrProtocol, "invalid target")
+			break
+		}
+		reply = krpc.NewResponse(msg.T, n.nodesReturn(target))
+	case krpc.MethodGetPeers:
+		infoHash, ok := toID(msg.A.InfoHash)
+		if !ok {
+			reply = krpc.NewError(msg.T, krpc.ErrProtocol, "invalid info_hash")
+			break
+		}
+		reply = krpc.NewResponse(msg.T, n.peersReturn(infoHash, from))
+	case krpc.MethodAnnouncePeer:
+		reply = n.announce(msg, from)
+	default:
+		reply = krpc.NewError(msg.T, krpc.ErrMethodUnknown, "Method Unknown")
+	}
+	n.send(reply, from)
+}
+
+func (n *Node) nodesReturn(target krpc.ID) krpc.Return {
+	var nodes, nodes6 []krpc.NodeInfo
+	for _, node := range n.table.Closest(target, K) {
+		if node.Addr.Addr().Is4() {
+			nodes = append(nodes, node)
+		} else {
+			nodes6 = append(nodes6, node)
+		}
+	}
+	return krpc.Return{ID: string(n.id[:]), Nodes: krpc.CompactNodes(nodes), Nodes6: krpc.CompactNodes(nodes6)}
+}
+
+func (n *Node) peersReturn(infoHash krpc.ID, from netip.AddrPort) krpc.Return {
+	n.mu.Lock()
+	var values []string
+	for peer := range n.peers[infoHash] {
+		values = append(values, krpc.CompactPeer(peer))
+	}
+	n.mu.Unlock()
+
+	r := krpc.Return{ID: string(n.id[:]), Token: n.tokens.token(from.Addr()), Values: values}
+	if len(values) == 0 {
+		nodes := n.nodesReturn(infoHash)
+		r.Nodes, r.Nodes6 = nodes.Nodes, nodes.Nodes6
+	}
+	return r
+}
+
+func (n *Node) announce(msg *krpc.Message, from netip.AddrPort) *krpc.Message {
+	infoHash, ok := toID(msg.A.InfoHash)
+	if !ok {
+		return krpc.NewError(msg.T, krpc.ErrProtocol, "invalid info_hash")
+	}
+	if !n.tokens.valid(msg.A.Token, from.Addr()) {
+		return krpc.NewError(msg.T, krpc.ErrProtocol, "bad token")
+	}
+	port := from.Port()
+	if msg.A.ImpliedPort == 0 {
+		if msg.A.Port <= 0 || msg.A.Port > 65535 {
+			return krpc.NewError(msg.T, krpc.ErrProtocol, "invalid port")
+		}
+		port = uint16(msg.A.Port)
+	}
+
+	n.mu.Lock()
+	if n.peers[infoHash] == nil {
+		n.peers[infoHash] = make(map[netip.AddrPort]bool)
+	}
+	n.peers[infoHash][netip.AddrPortFrom(from.Addr(), port)] = true
+	n.mu.Unlock()
+	return krpc.NewResponse(msg.T, krpc.Return{ID: string(n.id[:])})
+}
+
+func (n *Node) send(msg *krpc.Message, to netip.AddrPort) error {
+	data, err := krpc.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = n.conn.WriteTo(data, net.UDPAddrFromAddrPort(to))
+	return err
+}
+
+// query sends a query and waits for the response. A KRPC error response is
+// returned as *krpc.Error.
+func (n *Node) query(ctx context.Context, to netip.AddrPort, method string, args krpc.Args) (*krpc.Return, error) {
+	args.ID = string(n.id[:])
+
+	n.mu.Lock()
+	n.nextT++
+	var t [2]byte
+	binary.BigEndian.PutUint16(t[:], n.nextT)
+	ch := make(chan *krpc.Message, 1)
+	n.pending[string(t[:])] = ch
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.pending, string(t[:]))
+		n.mu.Unlock()
+	}()
+
+	if err := n.send(krpc.NewQuery(string(t[:]), method, args), to); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(n.timeout)
+	defer timer.Stop()
+	select {
+	case msg := <-ch:
+		if msg.Y == krpc.TypeError {
+			return nil, msg.E
+		}
+		if id, ok := toID(msg.R.ID); ok {
+			n.table.Add(krpc.NodeInfo{ID: id, Addr: to})
+		}
+		return msg.R, nil
+	case <-timer.C:
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Ping queries the node at addr and returns its ID.
+func (n *Node) Ping(ctx context.Context, addr netip.AddrPort) (krpc.ID, error) {
+	r, err := n.query(ctx, addr, krpc.MethodPing, krpc.Args{})
+	if err != nil {
+		return krpc.ID{}, err
+	}
+	id, _ := toID(r.ID)
+	return id, nil
+}
+
+// FindNode asks the node at addr for the nodes closest to target.
+func (n *Node) FindNode(ctx context.Context, addr netip.AddrPort, target krpc.ID) ([]krpc.NodeInfo, error) {
+	r, err := n.query(ctx, addr, krpc.MethodFindNode, krpc.Args{Target: string(target[:])})
+	if err != nil {
+		return nil, err
+	}
+	return r.NodeInfos()
+}
+
+// GetPeersResult is the response of a get_peers query.
+type GetPeersResult struct {
+	Peers []netip.AddrPort
+	Nodes []krpc.NodeInfo
+	Token string
+}
+
+// GetPeers asks the node at addr for the peers of infoHash.
+func (n *Node) GetPeers(ctx context.Context, addr netip.AddrPort, infoHash krpc.ID) (*GetPeersResult, error) {
+	r, err := n.query(ctx, addr, krpc.MethodGetPeers, krpc.Args{InfoHash: string(infoHash[:])})
+	if err != nil {
+		return nil, err
+	}
+	res := &GetPeersResult{Token: r.Token}
+	if res.Peers, err = r.Peers(); err != nil {
+		return nil, err
+	}
+	if res.Nodes, err = r.NodeInfos(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AnnouncePeer tells the node at addr that this node's peer downloads
+// infoHash on the given port. A zero port announces the port the node
+// listens on. The token comes from a previous GetPeers to the same node.
+func (n *Node) AnnouncePeer(ctx context.Context, addr netip.AddrPort, infoHash krpc.ID, port int, token string) error {
+	args := krpc.Args{InfoHash: string(infoHash[:]), Port: port, Token: token}
+	if port == 0 {
+		args.ImpliedPort = 1
+	}
+	_, err := n.query(ctx, addr, krpc.MethodAnnouncePeer, args)
+	return err
+}
+
+// Bootstrap pings the given nodes and then looks up the node's own ID to
+// fill the routing table.
+func (n *Node) Bootstrap(ctx context.Context, addrs ...netip.AddrPort) error {
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr netip.AddrPort) {
+			defer wg.Done()
+			n.Ping(ctx, addr)
+		}(addr)
+	}
+	wg.Wait()
+	if n.table.Len() == 0 {
+		return errors.New("dht: no bootstrap node responded")
+	}
+	_, err := n.Lookup(ctx, n.id)
+	return err
+}
+
+// Lookup iteratively queries the nodes closest to target and returns the
+// K closest nodes that responded.
+func (n *Node) Lookup(ctx context.Context, target krpc.ID) ([]krpc.NodeInfo, error) {
+	l := n.lookup(ctx, target, func(ctx context.Context, node krpc.NodeInfo) ([]krpc.NodeInfo, error) {
+		return n.FindNode(ctx, node.Addr, target)
+	})
+	return l, ctx.Err()
+}
+
+// LookupPeers iteratively searches for the peers of infoHash. If port is
+// not negative, it also announces this node's peer to the closest nodes
+// found, with port as in AnnouncePeer.
+func (n *Node) LookupPeers(ctx context.Context, infoHash krpc.ID, port int) ([]netip.AddrPort, error) {
+	var (
+		mu     sync.Mutex
+		peers  = make(map[netip.AddrPort]bool)
+		tokens = make(map[krpc.ID]string)
+	)
+	closest := n.lookup(ctx, infoHash, func(ctx context.Context, node krpc.NodeInfo) ([]krpc.NodeInfo, error) {
+		res, err := n.GetPeers(ctx, node.Addr, infoHash)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		for _, p := range res.Peers {
+			peers[p] = true
+		}
+		tokens[node.ID] = res.Token
+		mu.Unlock()
+		return res.Nodes, nil
+	})
+
+	if port >= 0 {
+		for _, node := range closest {
+			if token, ok := tokens[node.ID]; ok {
+				n.AnnouncePeer(ctx, node.Addr, infoHash, port, token)
+			}
+		}
+	}
+
+	list := make([]netip.AddrPort, 0, len(peers))
+	for p := range peers {
+		list = append(list, p)
+	}
+	return list, ctx.Err()
+}
+
+// lookup runs an iterative lookup with the given query and returns the
+// K closest nodes which responded.
+func (n *Node) lookup(ctx context.Context, target krpc.ID, query func(context.Context, krpc.NodeInfo) ([]krpc.NodeInfo, error)) []krpc.NodeInfo {
+	var (
+		mu        sync.Mutex
+		seen      = make(map[krpc.ID]bool)
+		queried   = make(map[krpc.ID]bool)
+		responded []krpc.NodeInfo
+		shortlist []krpc.NodeInfo
+	)
+	add := func(nodes []krpc.NodeInfo) {
+		for _, node := range nodes {
+			if !seen[node.ID] && node.ID != n.id {
+				seen[node.ID] = true
+				shortlist = append(shortlist, node)
+			}
+		}
+		sortByDistance(shortlist, target)
+	}
+	add(n.table.Closest(target, K))
+
+	for ctx.Err() == nil {
+		// query up to alpha unqueried nodes among the K closest
+		var batch []krpc.NodeInfo
+		for i := 0; i < len(shortlist) && i < K && len(batch) < alpha; i++ {
+			if !queried[shortlist[i].ID] {
+				queried[shortlist[i].ID] = true
+				batch = append(batch, shortlist[i])
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		var found []krpc.NodeInfo
+		for _, node := range batch {
+			wg.Add(1)
+			go func(node krpc.NodeInfo) {
+				defer wg.Done()
+				nodes, err := query(ctx, node)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					return
+				}
+				responded = append(responded, node)
+				found = append(found, nodes...)
+			}(node)
+		}
+		wg.Wait()
+		add(found)
+	}
+
+	sortByDistance(responded, target)
+	if len(responded) > K {
+		responded = responded[:K]
+	}
+	return responded
+}
+
+func toID(s string) (krpc.ID, bool) {
+	var id krpc.ID
+	if len(s) != len(id) {
+		return id, false
+	}
+	copy(id[:], s)
+	return id, true
+}
@@ -0,0 +1,122 @@
+package dht
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ortymid/bencode/krpc"
+)
+
+// newSwarm starts n nodes on an in-memory network, all bootstrapped from
+// the first one.
+func newSwarm(t *testing.T, n int) []*Node {
+	network := NewMemNetwork()
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		addr := netip.AddrPortFrom(netip.MustParseAddr("10.0.0.1"), uint16(1000+i))
+		conn, err := network.Listen(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = NewNode(conn, sha1.Sum([]byte(strconv.Itoa(i))))
+		nodes[i].SetTimeout(time.Second)
+		go nodes[i].Serve()
+		t.Cleanup(func() { nodes[i].Close() })
+	}
+
+	ctx := context.Background()
+	for _, node := range nodes[1:] {
+		if err := node.Bootstrap(ctx, nodes[0].Addr()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	return nodes
+}
+
+func TestNodeQueries(t *testing.T) {
+	nodes := newSwarm(t, 2)
+	a, b := nodes[0], nodes[1]
+	ctx := context.Background()
+
+	id, err := a.Ping(ctx, b.Addr())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if id != b.ID() {
+		t.Errorf("got: %x want: %x", id, b.ID())
+	}
+
+	infoHash := krpc.ID{42}
+	res, err := a.GetPeers(ctx, b.Addr(), infoHash)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(res.Peers) != 0 || res.Token == "" {
+		t.Errorf("got: %+v", res)
+	}
+
+	if err := a.AnnouncePeer(ctx, b.Addr(), infoHash, 6881, "bad token"); err == nil {
+		t.Error("no error for a bad token")
+	} else if e := (*krpc.Error)(nil); !errors.As(err, &e) || e.Code != krpc.ErrProtocol {
+		t.Error("got:", err)
+	}
+	if err := a.AnnouncePeer(ctx, b.Addr(), infoHash, 6881, res.Token); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	res, err = a.GetPeers(ctx, b.Addr(), infoHash)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := netip.AddrPortFrom(a.Addr().Addr(), 6881)
+	if len(res.Peers) != 1 || res.Peers[0] != want {
+		t.Error("got:", res.Peers, "want:", want)
+	}
+}
+
+func TestNodeTimeout(t *testing.T) {
+	network := NewMemNetwork()
+	conn, _ := network.Listen(netip.MustParseAddrPort("10.0.0.1:1"))
+	node := NewNode(conn, krpc.ID{1})
+	node.SetTimeout(10 * time.Millisecond)
+	go node.Serve()
+	defer node.Close()
+
+	if _, err := node.Ping(context.Background(), netip.MustParseAddrPort("10.0.0.1:2")); err != ErrTimeout {
+		t.Error("got:", err, "want:", ErrTimeout)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	nodes := newSwarm(t, 40)
+	ctx := context.Background()
+
+	// the lookup finds the node which is the target
+	target := nodes[len(nodes)-1].ID()
+	found, err := nodes[1].Lookup(ctx, target)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(found) == 0 || found[0].ID != target {
+		t.Error("target node is not the closest found")
+	}
+
+	// a peer announced by one node is found by another one
+	infoHash := krpc.ID(sha1.Sum([]byte("torrent")))
+	if _, err := nodes[5].LookupPeers(ctx, infoHash, 6881); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	peers, err := nodes[20].LookupPeers(ctx, infoHash, -1)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := netip.AddrPortFrom(nodes[5].Addr().Addr(), 6881)
+	if len(peers) != 1 || peers[0] != want {
+		t.Error("got:", peers, "want:", want)
+	}
+}
@@ -0,0 +1,124 @@
+package dht
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/ortymid/bencode/krpc"
+)
+
+// K is the capacity of a bucket and the number of nodes a lookup returns.
+const K = 8
+
+// Table is a routing table of k-buckets. Bucket i holds the nodes whose IDs
+// share exactly i leading bits with the table's own ID. It is safe for
+// concurrent use.
+type Table struct {
+	mu      sync.Mutex
+	self    krpc.ID
+	buckets [krpc.IDSize * 8][]krpc.NodeInfo
+}
+
+// NewTable returns an empty routing table of the node with the given ID.
+func NewTable(self krpc.ID) *Table {
+	return &Table{self: self}
+}
+
+// Add inserts a node or marks it as recently seen. A node is not added if
+// its bucket is full, as long-lived nodes are preferred. It reports whether
+// the node is in the table.
+func (t *Table) Add(n krpc.NodeInfo) bool {
+	i := commonPrefixLen(t.self, n.ID)
+	if i == len(t.buckets) {
+		return false // that is us
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.buckets[i]
+	for j, m := range b {
+		if m.ID == n.ID {
+			// move to the tail, the most recently seen position
+			b = append(b[:j], b[j+1:]...)
+			t.buckets[i] = append(b, n)
+			return true
+		}
+	}
+	if len(b) >= K {
+		return false
+	}
+	t.buckets[i] = append(b, n)
+	return true
+}
+
+// Remove deletes the node with the given ID.
+func (t *Table) Remove(id krpc.ID) {
+	i := commonPrefixLen(t.self, id)
+	if i == len(t.buckets) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.buckets[i]
+	for j, m := range b {
+		if m.ID == id {
+			t.buckets[i] = append(b[:j], b[j+1:]...)
+			return
+		}
+	}
+}
+
+// Closest returns up to n nodes closest to target by XOR distance, the
+// closest first.
+func (t *Table) Closest(target krpc.ID, n int) []krpc.NodeInfo {
+	t.mu.Lock()
+	var nodes []krpc.NodeInfo
+	for _, b := range t.buckets {
+		nodes = append(nodes, b...)
+	}
+	t.mu.Unlock()
+
+	sortByDistance(nodes, target)
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
+
+// Len returns the number of nodes in the table.
+func (t *Table) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, b := range t.buckets {
+		n += len(b)
+	}
+	return n
+}
+
+// commonPrefixLen returns the number of leading bits a and b share.
+func commonPrefixLen(a, b krpc.ID) int {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			return i*8 + bits.LeadingZeros8(x)
+		}
+	}
+	return len(a) * 8
+}
+
+// closer reports whether a is closer to target than b.
+func closer(a, b, target krpc.ID) bool {
+	for i := range target {
+		da, db := a[i]^target[i], b[i]^target[i]
+		if da != db {
+			return da < db
+		}
+	}
+	return false
+}
+
+func sortByDistance(nodes []krpc.NodeInfo, target krpc.ID) {
+	sort.Slice(nodes, func(i, j int) bool { return closer(nodes[i].ID, nodes[j].ID, target) })
+}
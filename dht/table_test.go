@@ -0,0 +1,71 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/ortymid/bencode/krpc"
+)
+
+func node(first byte, port uint16) krpc.NodeInfo {
+	return krpc.NodeInfo{ID: krpc.ID{first}, Addr: netip.AddrPortFrom(netip.MustParseAddr("10.0.0.1"), port)}
+}
+
+func TestTable(t *testing.T) {
+	table := NewTable(krpc.ID{})
+
+	if table.Add(krpc.NodeInfo{ID: krpc.ID{}}) {
+		t.Error("added own ID")
+	}
+
+	// all IDs from 0x80 to 0xff share no prefix with the own ID
+	for i := 0; i < K+2; i++ {
+		added := table.Add(node(byte(0x80+i), uint16(i)))
+		if added != (i < K) {
+			t.Errorf("node %d: got added %v", i, added)
+		}
+	}
+	if table.Len() != K {
+		t.Error("got:", table.Len(), "want:", K)
+	}
+
+	// a known node is updated even when its bucket is full
+	if !table.Add(node(0x80, 100)) {
+		t.Error("known node was not updated")
+	}
+
+	table.Add(node(0x01, 1))
+	table.Add(node(0x02, 2))
+	got := table.Closest(krpc.ID{}, 3)
+	want := []krpc.ID{{0x01}, {0x02}, {0x80}}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Errorf("closest %d: got %x want %x", i, got[i].ID[:1], want[i][:1])
+		}
+	}
+	if got[2].Addr.Port() != 100 {
+		t.Error("got port:", got[2].Addr.Port(), "want:", 100)
+	}
+
+	table.Remove(krpc.ID{0x02})
+	if table.Len() != K+1 {
+		t.Error("got:", table.Len(), "want:", K+1)
+	}
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	tests := []struct {
+		a, b krpc.ID
+		want int
+	}{
+		{krpc.ID{}, krpc.ID{}, 160},
+		{krpc.ID{0x80}, krpc.ID{}, 0},
+		{krpc.ID{0x01}, krpc.ID{}, 7},
+		{krpc.ID{0xff, 0x10}, krpc.ID{0xff, 0x00}, 11},
+	}
+	for _, test := range tests {
+		if got := commonPrefixLen(test.a, test.b); got != test.want {
+			t.Errorf("%x %x: got %d want %d", test.a[:2], test.b[:2], got, test.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dht
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// tokenLifetime is how often the token secret changes. Tokens of the
+// previous secret are still accepted, as BEP 5 recommends.
+const tokenLifetime = 5 * time.Minute
+
+// tokens generates and validates announce_peer tokens, which are bound to
+// the IP address of the querying node.
+type tokens struct {
+	mu      sync.Mutex
+	secret  [16]byte
+	prev    [16]byte
+	rotated time.Time
+	now     func() time.Time
+}
+
+func newTokens() *tokens {
+	t := &tokens{now: time.Now}
+	rand.Read(t.secret[:])
+	t.prev = t.secret
+	t.rotated = t.now()
+	return t
+}
+
+// rotate changes the secret if it has expired. The caller holds t.mu.
+func (t *tokens) rotate() {
+	now := t.now()
+	if now.Sub(t.rotated) < tokenLifetime {
+		return
+	}
+	t.prev = t.secret
+	rand.Read(t.secret[:])
+	if now.Sub(t.rotated) >= 2*tokenLifetime {
+		t.prev = t.secret // the previous secret has expired too
+	}
+	t.rotated = now
+}
+
+// token returns the token for addr.
+func (t *tokens) token(addr netip.Addr) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rotate()
+	return sign(t.secret, addr)
+}
+
+// valid reports whether token was given out to addr recently.
+func (t *tokens) valid(token string, addr netip.Addr) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rotate()
+	return token == sign(t.secret, addr) || token == sign(t.prev, addr)
+}
+
+func sign(secret [16]byte, addr netip.Addr) string {
+	ip := addr.Unmap().AsSlice()
+	h := sha1.Sum(append(secret[:], ip...))
+	return string(h[:8])
+}
@@ -0,0 +1,35 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	now := time.Now()
+	tokens := newTokens()
+	tokens.now = func() time.Time { return now }
+	tokens.rotated = now
+
+	a := netip.MustParseAddr("10.0.0.1")
+	b := netip.MustParseAddr("10.0.0.2")
+	token := tokens.token(a)
+
+	if !tokens.valid(token, a) {
+		t.Error("fresh token is not valid")
+	}
+	if tokens.valid(token, b) {
+		t.Error("token is valid for another address")
+	}
+
+	now = now.Add(tokenLifetime)
+	if !tokens.valid(token, a) {
+		t.Error("token of the previous secret is not valid")
+	}
+
+	now = now.Add(tokenLifetime)
+	if tokens.valid(token, a) {
+		t.Error("expired token is valid")
+	}
+}
@@ -1,3 +1,428 @@
 package bencode
 
-// TODO: encode values
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ErrUnsupportedType describes an error which occurs when a value of a type
+// which has no bencode representation is passed to the Marshal function, or
+// is the destination of Unmarshal.
+type ErrUnsupportedType struct {
+	t reflect.Type
+}
+
+func (e *ErrUnsupportedType) Error() string {
+	return fmt.Sprintf("bencode: unsupported type %s", e.t)
+}
+
+// Is satisfies errors.Is requirements.
+func (e *ErrUnsupportedType) Is(err error) bool {
+	_, ok := err.(*ErrUnsupportedType)
+	return ok
+}
+
+// Marshaler is the interface implemented by types that can marshal
+// themselves into valid bencode.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// RawMessage is a raw bencoded value. It is written as is by Marshal, and
+// Unmarshal stores a copy of the exact bytes of a value in it.
+type RawMessage []byte
+
+// Bytes is a string held as bytes. Unlike a []byte, which Marshal writes as
+// raw bencoded data, Bytes is written as a bencoded string. A Decoder stores
+// strings as Bytes when decoding into interface{} with UseBytes.
+type Bytes []byte
+
+// MarshalBencode implements Marshaler.
+func (b Bytes) MarshalBencode() ([]byte, error) {
+	return String(b).Bencode(), nil
+}
+
+// UnmarshalBencode implements Unmarshaler.
+func (b *Bytes) UnmarshalBencode(data []byte) error {
+	s := NewScanner(data)
+	v, err := s.ReadString()
+	if err != nil {
+		return err
+	}
+	*b = append(Bytes{}, v...)
+	return nil
+}
+
+// Marshal returns the bencoding of v.
+//
+// Integers of any size become bencoded integers, strings become bencoded
+// strings, slices and arrays become lists, and maps with string keys and
+// structs become dicts with the keys sorted, as the canonical form requires.
+// A []byte is treated as raw bencoded data and is written as is. Values
+// implementing Value are written with the order of dict keys preserved, and
+// values implementing Marshaler are written as MarshalBencode returns them.
+// Number and big.Int values become bencoded integers of any size. A time.Time
+// becomes an integer of Unix seconds. Values
+// implementing encoding.BinaryMarshaler or encoding.TextMarshaler become
+// strings, preferring MarshalBinary, and may be map keys. Pointers and
+// interfaces are encoded as the value they point to.
+//
+// Struct fields are encoded under the name given in the "bencode" tag, or
+// under the field name if the tag has none. The "omitempty" option skips
+// a field with an empty value, and the "-" tag skips a field entirely. The
+// "rest" option marks a map[string]RawMessage or *Dict field which holds the
+// keys matching no other field; they are written along with the other fields
+// in sorted order. The "unixsec" and "unixms" options encode a time.Time
+// field in Unix seconds or milliseconds, and the "seconds" and "ms" options
+// a time.Duration field in whole seconds or milliseconds instead of
+// nanoseconds. Times must be within the years 1 to 9999.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writes bencoded values to an output stream.
+type Encoder struct {
+	writer io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: w}
+}
+
+// Encode writes the bencoding of v to the stream.
+func (e *Encoder) Encode(v interface{}) error {
+	val, err := e.get(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = val.WriteTo(e.writer)
+	return err
+}
+
+var (
+	valueType     = reflect.TypeOf((*Value)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+)
+
+// get dispatches getting a Value from reflect.Value depending on the Kind
+// of the source.
+func (e *Encoder) get(src reflect.Value) (Value, error) {
+	if !src.IsValid() {
+		return nil, fmt.Errorf("bencode: cannot encode nil")
+	}
+	if src.Type().Implements(valueType) {
+		if (src.Kind() == reflect.Ptr || src.Kind() == reflect.Interface) && src.IsNil() {
+			return nil, fmt.Errorf("bencode: cannot encode nil %s", src.Type())
+		}
+		return src.Interface().(Value), nil
+	}
+	if src.Kind() != reflect.Ptr && src.CanAddr() && src.Addr().Type().Implements(marshalerType) {
+		src = src.Addr()
+	}
+	if src.Type().Implements(marshalerType) {
+		if src.Kind() == reflect.Ptr && src.IsNil() {
+			return nil, fmt.Errorf("bencode: cannot encode nil %s", src.Type())
+		}
+		return e.getMarshaler(src.Interface().(Marshaler))
+	}
+	switch src.Type() {
+	case numberType:
+		return e.getNumber(src.String())
+	case bigIntType:
+		n := src.Interface().(big.Int)
+		return e.getNumber(n.String())
+	case timeType:
+		return e.getTime(src, 0)
+	case reflect.PtrTo(bigIntType), reflect.PtrTo(timeType):
+		// not as text, which these types implement
+		if !src.IsNil() {
+			return e.get(src.Elem())
+		}
+	}
+	if src.Kind() != reflect.Ptr && src.CanAddr() && isTextMarshaler(src.Addr().Type()) {
+		src = src.Addr()
+	}
+	if isTextMarshaler(src.Type()) {
+		b, err := marshalText(src)
+		if err != nil {
+			return nil, err
+		}
+		return String(b), nil
+	}
+
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(src.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := src.Uint()
+		if u > 1<<63-1 {
+			return nil, fmt.Errorf("bencode: %d overflows bencode integer", u)
+		}
+		return Int(u), nil
+	case reflect.String:
+		return String(src.String()), nil
+	case reflect.Slice:
+		if src.Type().Elem().Kind() == reflect.Uint8 {
+			return e.getBencode(src)
+		}
+		return e.getList(src)
+	case reflect.Array:
+		return e.getList(src)
+	case reflect.Map:
+		return e.getDict(src)
+	case reflect.Struct:
+		return e.getStruct(src)
+	case reflect.Ptr, reflect.Interface:
+		if src.IsNil() {
+			return nil, fmt.Errorf("bencode: cannot encode nil %s", src.Type())
+		}
+		return e.get(src.Elem())
+	}
+
+	return nil, &ErrUnsupportedType{src.Type()}
+}
+
+func (e *Encoder) getList(src reflect.Value) (Value, error) {
+	list := make(List, src.Len())
+	for i := range list {
+		v, err := e.get(src.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (e *Encoder) getDict(src reflect.Value) (Value, error) {
+	// only strings allowed to be the keys in bencode
+	mapKeyType := src.Type().Key()
+	if mapKeyType.Kind() != reflect.String && !isTextMarshaler(mapKeyType) {
+		return nil, fmt.Errorf("bencode: map keys must be strings or implement encoding.TextMarshaler or encoding.BinaryMarshaler, not %v", mapKeyType)
+	}
+
+	items := make([]DictItem, 0, src.Len())
+	iter := src.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		key := k.String()
+		if k.Kind() != reflect.String {
+			b, err := marshalText(k)
+			if err != nil {
+				return nil, err
+			}
+			key = string(b)
+		}
+		v, err := e.get(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, DictItem{String(key), v})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+
+	dict := NewDict(items...)
+	if len(dict.keys) != len(items) {
+		return nil, fmt.Errorf("bencode: duplicate map key in %v", src.Type())
+	}
+	return dict, nil
+}
+
+func (e *Encoder) getStruct(src reflect.Value) (Value, error) {
+	var items []DictItem
+	var keys []string
+	var rest reflect.Value
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		key, opts, ok := fieldKey(field)
+		if !ok {
+			continue
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("bencode: struct field %s must be exported", field.Name)
+		}
+		fv := src.Field(i)
+		unit, err := timeUnit(field, opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Contains("rest") {
+			if err := checkRest(field, rest.IsValid()); err != nil {
+				return nil, err
+			}
+			rest = fv
+			continue
+		}
+		keys = append(keys, key)
+		if opts.Contains("omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		var v Value
+		if unit != 0 {
+			v, err = e.getTime(fv, unit)
+		} else {
+			v, err = e.get(fv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, DictItem{String(key), v})
+	}
+	if rest.IsValid() {
+		extra, err := e.getRest(rest, keys)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, extra...)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return NewDict(items...), nil
+}
+
+// getRest returns the items of a rest field, except for those under the
+// keys of the other fields of the struct.
+func (e *Encoder) getRest(rest reflect.Value, keys []string) ([]DictItem, error) {
+	if rest.IsNil() {
+		return nil, nil
+	}
+
+	var items []DictItem
+	if dict, ok := rest.Interface().(*Dict); ok {
+		for _, k := range dict.keys {
+			if !containsString(keys, string(k)) {
+				items = append(items, DictItem{k, dict.m[k]})
+			}
+		}
+		return items, nil
+	}
+
+	iter := rest.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		if containsString(keys, k) {
+			continue
+		}
+		v, err := e.getBencode(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, DictItem{String(k), v})
+	}
+	return items, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getTime returns the bencoded integer of a time.Time or time.Duration, or
+// of a pointer to one, in the unit.
+func (e *Encoder) getTime(src reflect.Value, unit time.Duration) (Value, error) {
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			return nil, fmt.Errorf("bencode: cannot encode nil %s", src.Type())
+		}
+		src = src.Elem()
+	}
+	if src.Type() == timeType {
+		return encodeTime(src.Interface().(time.Time), unit)
+	}
+	return encodeDuration(time.Duration(src.Int()), unit), nil
+}
+
+// getNumber returns the bencoded integer of the decimal form n.
+func (e *Encoder) getNumber(n string) (Value, error) {
+	b := []byte("i" + n + "e")
+	s := NewScanner(b)
+	if _, err := s.readNumber(); err != nil || s.Finish() != nil {
+		return nil, fmt.Errorf("bencode: invalid number %q", n)
+	}
+	return rawValue(b), nil
+}
+
+func (e *Encoder) getMarshaler(m Marshaler) (Value, error) {
+	b, err := m.MarshalBencode()
+	if err != nil {
+		return nil, err
+	}
+	return e.getBencode(reflect.ValueOf(b))
+}
+
+func (e *Encoder) getBencode(src reflect.Value) (Value, error) {
+	b := src.Bytes()
+	p := NewParser(bytes.NewReader(b))
+	if _, err := p.Parse(); err != nil {
+		return nil, fmt.Errorf("bencode: invalid raw bencode: %w", err)
+	}
+	if p.offset != int64(len(b)) {
+		return nil, fmt.Errorf("bencode: invalid raw bencode: unexpected data after value")
+	}
+	return rawValue(b), nil
+}
+
+// isEmptyValue reports whether v is empty in the sense of the "omitempty"
+// tag option.
+func isEmptyValue(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// rawValue is an already bencoded value which is written as is.
+type rawValue []byte
+
+// Interface returns the parsed value put into interface{}.
+func (r rawValue) Interface() interface{} {
+	v, err := NewParser(bytes.NewReader(r)).Parse()
+	if err != nil {
+		return nil
+	}
+	return v.Interface()
+}
+
+// Bencode returns the raw value.
+func (r rawValue) Bencode() []byte {
+	return append([]byte(nil), r...)
+}
+
+// AppendBencode appends the raw value to dst.
+func (r rawValue) AppendBencode(dst []byte) []byte {
+	return append(dst, r...)
+}
+
+// EncodedLen returns the length of the raw value.
+func (r rawValue) EncodedLen() int {
+	return len(r)
+}
+
+// WriteTo writes the raw value to w.
+func (r rawValue) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r)
+	return int64(n), err
+}
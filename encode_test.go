@@ -0,0 +1,175 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type Inner struct {
+		A int64 `bencode:"a"`
+	}
+	type TestStruct struct {
+		Z       string           `bencode:"z"`
+		A       []int            `bencode:"a"`
+		M       map[string]uint8 `bencode:"m"`
+		Inner   Inner            `bencode:"inner"`
+		Ptr     *Inner           `bencode:"ptr,omitempty"`
+		Raw     []byte           `bencode:"raw"`
+		Empty   string           `bencode:"empty,omitempty"`
+		Skipped string           `bencode:"-"`
+		Named   int32
+		Dict    *Dict             `bencode:"dict"`
+		Extra   map[string]string `bencode:"extra,omitempty"`
+	}
+
+	tests := []struct {
+		name  string
+		input interface{}
+		want  string
+	}{
+		{"Int", 42, `i42e`},
+		{"Uint", uint16(42), `i42e`},
+		{"String", "spam", `4:spam`},
+		{"Slice", []string{"spam", "eggs"}, `l4:spam4:eggse`},
+		{"Array", [2]int{1, 2}, `li1ei2ee`},
+		{"Map", map[string]int{"spam": 1, "eggs": 2}, `d4:eggsi2e4:spami1ee`},
+		{"Pointer", &Inner{42}, `d1:ai42ee`},
+		{"Interface", []interface{}{"spam", 42}, `l4:spami42ee`},
+		{"Value", NewDict([]DictItem{{String("z"), Int(1)}, {String("a"), Int(2)}}...), `d1:zi1e1:ai2ee`},
+		{"Struct", TestStruct{
+			Z:       "spam",
+			A:       []int{1},
+			M:       map[string]uint8{"b": 2},
+			Inner:   Inner{42},
+			Raw:     []byte(`d1:zi0e1:ai0ee`),
+			Skipped: "skipped",
+			Named:   7,
+			Dict:    NewDict([]DictItem{{String("z"), Int(1)}, {String("a"), Int(2)}}...),
+		}, `d5:Namedi7e1:ali1ee4:dictd1:zi1e1:ai2ee5:innerd1:ai42ee1:md1:bi2ee3:rawd1:zi0e1:ai0ee1:z4:spame`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Marshal(test.input)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("\ngot: %s \nwant: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+	}{
+		{"Nil", nil},
+		{"Nil pointer", (*int)(nil)},
+		{"Float", 1.5},
+		{"Bool", true},
+		{"Map key", map[int]int{1: 1}},
+		{"Uint overflow", uint64(1 << 63)},
+		{"Invalid raw", []byte(`i1`)},
+		{"Trailing raw", []byte(`i1ei2e`)},
+		{"Unexported", struct{ a int }{1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Marshal(test.input); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+
+	t.Run("Unsupported type", func(t *testing.T) {
+		_, got := Marshal(1.5)
+		var want *ErrUnsupportedType
+		if !errors.Is(got, want) {
+			t.Error("got:", got, "want:", want)
+		}
+	})
+}
+
+func TestMarshalRoundtrip(t *testing.T) {
+	type TestStruct struct {
+		A int8              `bencode:"a"`
+		B *string           `bencode:"b"`
+		C []uint            `bencode:"c"`
+		D map[string]string `bencode:"d"`
+	}
+	b := "spam"
+	want := TestStruct{A: -1, B: &b, C: []uint{1, 2}, D: map[string]string{"spam": "eggs"}}
+
+	data, err := Marshal(want)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var got TestStruct
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if got.A != want.A || *got.B != *want.B || len(got.C) != 2 || got.D["spam"] != "eggs" {
+		t.Errorf("\ngot: %v \nwant: %v", got, want)
+	}
+}
+
+// pair is encoded as a two-item list of an integer and a string.
+type pair struct {
+	N int64
+	S string
+}
+
+func (p pair) MarshalBencode() ([]byte, error) {
+	return Marshal([]interface{}{p.N, p.S})
+}
+
+func (p *pair) UnmarshalBencode(data []byte) error {
+	var l []interface{}
+	if err := Unmarshal(data, &l); err != nil {
+		return err
+	}
+	if len(l) != 2 {
+		return errors.New("want two items")
+	}
+	n, ok1 := l[0].(int64)
+	s, ok2 := l[1].(string)
+	if !ok1 || !ok2 {
+		return errors.New("want an integer and a string")
+	}
+	p.N, p.S = n, s
+	return nil
+}
+
+func TestMarshaler(t *testing.T) {
+	type TestStruct struct {
+		P   pair  `bencode:"p"`
+		Ptr *pair `bencode:"ptr"`
+	}
+	input := TestStruct{P: pair{1, "spam"}, Ptr: &pair{2, "eggs"}}
+	want := `d1:pli1e4:spame3:ptrli2e4:eggsee`
+
+	got, err := Marshal(input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(got) != want {
+		t.Errorf("\ngot: %s \nwant: %s", got, want)
+	}
+
+	var back TestStruct
+	if err := Unmarshal(got, &back); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if back.P != input.P || *back.Ptr != *input.Ptr {
+		t.Errorf("\ngot: %+v \nwant: %+v", back, input)
+	}
+
+	if err := Unmarshal([]byte(`d1:pl4:spami1eee`), &back); err == nil {
+		t.Error("no error from UnmarshalBencode")
+	}
+}
@@ -0,0 +1,143 @@
+package extension
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// MaxMetadataSize is the largest metadata size an assembler accepts.
+const MaxMetadataSize = 64 << 20
+
+// ErrMetadataHash is returned when the assembled metadata does not match
+// the expected info-hash.
+var ErrMetadataHash = errors.New("extension: metadata does not match the info-hash")
+
+// MetadataAssembler collects the pieces of the info dict received in
+// ut_metadata data messages, in any order and from any number of peers,
+// and rebuilds the info dict. It is safe for concurrent use.
+type MetadataAssembler struct {
+	size int64
+	v1   *metainfo.HashV1
+	v2   *metainfo.HashV2
+
+	mu     sync.Mutex
+	pieces [][]byte
+	count  int
+}
+
+// NewMetadataAssembler returns an assembler of metadata of the given size,
+// as announced in the extended handshake. The assembled bytes are verified
+// against each of the info-hashes which are not nil; at least one is
+// required.
+func NewMetadataAssembler(size int64, v1 *metainfo.HashV1, v2 *metainfo.HashV2) (*MetadataAssembler, error) {
+	if size <= 0 || size > MaxMetadataSize {
+		return nil, fmt.Errorf("extension: invalid metadata size %d", size)
+	}
+	if v1 == nil && v2 == nil {
+		return nil, errors.New("extension: no info-hash to verify metadata against")
+	}
+	n := (size + MetadataPieceSize - 1) / MetadataPieceSize
+	return &MetadataAssembler{size: size, v1: v1, v2: v2, pieces: make([][]byte, n)}, nil
+}
+
+// NumPieces returns the number of metadata pieces.
+func (a *MetadataAssembler) NumPieces() int {
+	return len(a.pieces)
+}
+
+// pieceSize returns the expected size of the piece i.
+func (a *MetadataAssembler) pieceSize(i int) int {
+	if i == len(a.pieces)-1 {
+		return int(a.size - int64(i)*MetadataPieceSize)
+	}
+	return MetadataPieceSize
+}
+
+// Add stores the piece i. It reports whether all pieces are in. Adding a
+// piece which is already present replaces it.
+func (a *MetadataAssembler) Add(i int, data []byte) (bool, error) {
+	if i < 0 || i >= len(a.pieces) {
+		return false, fmt.Errorf("extension: metadata piece %d out of range", i)
+	}
+	if len(data) != a.pieceSize(i) {
+		return false, fmt.Errorf("extension: metadata piece %d has %d bytes, want %d", i, len(data), a.pieceSize(i))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pieces[i] == nil {
+		a.count++
+	}
+	a.pieces[i] = append([]byte(nil), data...)
+	return a.count == len(a.pieces), nil
+}
+
+// AddMessage stores the piece of a data message. The total size of the
+// message, if set, must match the size of the assembler.
+func (a *MetadataAssembler) AddMessage(m *MetadataMessage) (bool, error) {
+	if m.Type != MetadataData {
+		return false, fmt.Errorf("extension: metadata message of type %d carries no data", m.Type)
+	}
+	if m.TotalSize != 0 && m.TotalSize != a.size {
+		return false, fmt.Errorf("extension: metadata total size %d, want %d", m.TotalSize, a.size)
+	}
+	return a.Add(m.Piece, m.Data)
+}
+
+// Missing returns the indexes of the pieces which are not in yet.
+func (a *MetadataAssembler) Missing() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var missing []int
+	for i, p := range a.pieces {
+		if p == nil {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// Bytes returns the assembled info dict once all pieces are in and match
+// the info-hash. On a mismatch all pieces are discarded, since there is no
+// telling which of them is wrong, and ErrMetadataHash is returned.
+func (a *MetadataAssembler) Bytes() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.count != len(a.pieces) {
+		return nil, fmt.Errorf("extension: %d of %d metadata pieces are missing", len(a.pieces)-a.count, len(a.pieces))
+	}
+
+	data := make([]byte, 0, a.size)
+	for _, p := range a.pieces {
+		data = append(data, p...)
+	}
+	if (a.v1 != nil && sha1.Sum(data) != *a.v1) || (a.v2 != nil && sha256.Sum256(data) != *a.v2) {
+		a.pieces = make([][]byte, len(a.pieces))
+		a.count = 0
+		return nil, ErrMetadataHash
+	}
+	return data, nil
+}
+
+// Info returns the assembled and verified info dict, decoded and
+// validated.
+func (a *MetadataAssembler) Info() (*metainfo.Info, error) {
+	data, err := a.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	info := &metainfo.Info{}
+	if err := bencode.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,116 @@
+package extension
+
+import (
+	"crypto/sha1"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// testInfo returns an info dict which spans two metadata pieces.
+func testInfo(t *testing.T) (*metainfo.Info, []byte) {
+	const n = 1000
+	length := int64(n * metainfo.BlockSize)
+	info := &metainfo.Info{
+		PieceLength: metainfo.BlockSize,
+		Pieces:      strings.Repeat("\xaa", n*metainfo.HashSize),
+		Name:        "spam",
+		Length:      &length,
+	}
+	data, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return info, data
+}
+
+func TestMetadataAssembler(t *testing.T) {
+	info, data := testInfo(t)
+	hash := metainfo.HashV1(sha1.Sum(data))
+	a, err := NewMetadataAssembler(int64(len(data)), &hash, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if a.NumPieces() != 2 {
+		t.Fatal("got:", a.NumPieces(), "want:", 2)
+	}
+
+	// the last piece first, in a data message
+	done, err := a.AddMessage(&MetadataMessage{Type: MetadataData, Piece: 1, TotalSize: int64(len(data)), Data: data[MetadataPieceSize:]})
+	if err != nil || done {
+		t.Fatal("got:", done, err, "want: not done")
+	}
+	if got := a.Missing(); !reflect.DeepEqual(got, []int{0}) {
+		t.Error("got:", got, "want:", []int{0})
+	}
+	if _, err := a.Info(); err == nil {
+		t.Error("no error for missing pieces")
+	}
+
+	if done, err := a.Add(0, data[:MetadataPieceSize]); err != nil || !done {
+		t.Fatal("got:", done, err, "want: done")
+	}
+	got, err := a.Info()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(got, info) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, info)
+	}
+}
+
+func TestMetadataAssemblerMismatch(t *testing.T) {
+	_, data := testInfo(t)
+	hash := metainfo.HashV1(sha1.Sum(data))
+	a, err := NewMetadataAssembler(int64(len(data)), &hash, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	bad := append([]byte(nil), data[:MetadataPieceSize]...)
+	bad[0] ^= 1
+	a.Add(0, bad)
+	a.Add(1, data[MetadataPieceSize:])
+	if _, err := a.Bytes(); !errors.Is(err, ErrMetadataHash) {
+		t.Fatal("got:", err, "want:", ErrMetadataHash)
+	}
+	if got := a.Missing(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Error("got:", got, "want:", []int{0, 1})
+	}
+}
+
+func TestMetadataAssemblerError(t *testing.T) {
+	hash := metainfo.HashV1{}
+	if _, err := NewMetadataAssembler(0, &hash, nil); err == nil {
+		t.Error("no error for zero size")
+	}
+	if _, err := NewMetadataAssembler(1, nil, nil); err == nil {
+		t.Error("no error for no info-hash")
+	}
+
+	a, err := NewMetadataAssembler(MetadataPieceSize+1, &hash, nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	tests := []struct {
+		name  string
+		piece int
+		size  int
+	}{
+		{"Negative piece", -1, MetadataPieceSize},
+		{"Out of range", 2, 1},
+		{"Short piece", 0, MetadataPieceSize - 1},
+		{"Long last piece", 1, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := a.Add(test.piece, make([]byte, test.size)); err == nil {
+				t.Error("no error for piece", test.piece, "of", test.size, "bytes")
+			}
+		})
+	}
+}
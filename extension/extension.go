@@ -0,0 +1,78 @@
+// Package extension provides the messages of the extension protocol of the
+// peer wire protocol (BEP 10): the extended handshake, metadata exchange
+// (BEP 9, ut_metadata) and peer exchange (BEP 11, ut_pex). The functions
+// work with message payloads, without the length prefix, the message id 20
+// and the extended message id.
+package extension
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/ortymid/bencode"
+)
+
+// HandshakeID is the extended message id of the handshake.
+const HandshakeID = 0
+
+// Extension names, the keys of the "m" dict.
+const (
+	Metadata = "ut_metadata"
+	PEX      = "ut_pex"
+)
+
+// Handshake is the extended handshake. M maps extension names to the
+// message ids the sender wants to receive them with; id 0 disables an
+// extension.
+type Handshake struct {
+	M map[string]int `bencode:"m"`
+	// V is the client name and version.
+	V string `bencode:"v,omitempty"`
+	// P is the local TCP listen port.
+	P int `bencode:"p,omitempty"`
+	// YourIP is the compact IP address of the receiver as seen by the sender.
+	YourIP string `bencode:"yourip,omitempty"`
+	// Reqq is the number of outstanding requests the sender supports.
+	Reqq int `bencode:"reqq,omitempty"`
+	// MetadataSize is the size of the info dict in bytes (BEP 9).
+	MetadataSize int64 `bencode:"metadata_size,omitempty"`
+}
+
+// MarshalHandshake returns the payload of the handshake h.
+func MarshalHandshake(h *Handshake) ([]byte, error) {
+	if h.M == nil {
+		return nil, errors.New("extension: handshake has no m dict")
+	}
+	return bencode.Marshal(h)
+}
+
+// UnmarshalHandshake parses the payload of a handshake. Unknown keys are
+// ignored.
+func UnmarshalHandshake(data []byte) (*Handshake, error) {
+	h := &Handshake{}
+	if err := bencode.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	if h.M == nil {
+		return nil, errors.New("extension: handshake has no m dict")
+	}
+	return h, nil
+}
+
+// ID returns the message id the sender of h wants to receive the extension
+// with, and whether the extension is enabled.
+func (h *Handshake) ID(name string) (int, bool) {
+	id, ok := h.M[name]
+	return id, ok && id > 0
+}
+
+// YourAddr returns YourIP as an address.
+func (h *Handshake) YourAddr() (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice([]byte(h.YourIP))
+	return addr.Unmap(), ok
+}
+
+// SetYourAddr sets YourIP to the compact form of addr.
+func (h *Handshake) SetYourAddr(addr netip.Addr) {
+	h.YourIP = string(addr.Unmap().AsSlice())
+}
@@ -0,0 +1,105 @@
+package extension
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestHandshake(t *testing.T) {
+	input := "d1:md11:ut_metadatai3e6:ut_pexi0ee13:metadata_sizei31235e1:pi6881e4:reqqi500e1:v10:Spam 1.0.06:yourip4:\x01\x02\x03\x04e"
+	want := &Handshake{
+		M:            map[string]int{"ut_metadata": 3, "ut_pex": 0},
+		V:            "Spam 1.0.0",
+		P:            6881,
+		YourIP:       "\x01\x02\x03\x04",
+		Reqq:         500,
+		MetadataSize: 31235,
+	}
+
+	got, err := UnmarshalHandshake([]byte(input))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, want)
+	}
+	if id, ok := got.ID(Metadata); id != 3 || !ok {
+		t.Error("got:", id, ok, "want:", 3, true)
+	}
+	if _, ok := got.ID(PEX); ok {
+		t.Error("got: disabled extension enabled")
+	}
+	if addr, ok := got.YourAddr(); !ok || addr != netip.MustParseAddr("1.2.3.4") {
+		t.Error("got:", addr, "want: 1.2.3.4")
+	}
+
+	data, err := MarshalHandshake(got)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(data) != input {
+		t.Errorf("\ngot: %q \nwant: %q", data, input)
+	}
+}
+
+func TestHandshakeError(t *testing.T) {
+	if _, err := UnmarshalHandshake([]byte("d1:v4:spame")); err == nil {
+		t.Error("no error for handshake without m")
+	}
+	if _, err := MarshalHandshake(&Handshake{}); err == nil {
+		t.Error("no error for handshake without m")
+	}
+}
+
+func TestMetadataMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *MetadataMessage
+	}{
+		{"Request", "d8:msg_typei0e5:piecei0ee", &MetadataMessage{Type: MetadataRequest}},
+		{"Data", "d8:msg_typei1e5:piecei1e10:total_sizei16388eeabc\x00", &MetadataMessage{Type: MetadataData, Piece: 1, TotalSize: 16388, Data: []byte("abc\x00")}},
+		{"Data/Looks bencoded", "d8:msg_typei1e5:piecei0e10:total_sizei5eed1:ae", &MetadataMessage{Type: MetadataData, TotalSize: 5, Data: []byte("d1:ae")}},
+		{"Reject", "d8:msg_typei2e5:piecei2ee", &MetadataMessage{Type: MetadataReject, Piece: 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnmarshalMetadata([]byte(test.input))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", got, test.want)
+			}
+			data, err := MarshalMetadata(got)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(data) != test.input {
+				t.Errorf("\ngot: %q \nwant: %q", data, test.input)
+			}
+		})
+	}
+}
+
+func TestMetadataMessageError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Unknown type", "d8:msg_typei3e5:piecei0ee"},
+		{"Request with data", "d8:msg_typei0e5:piecei0eex"},
+		{"Negative piece", "d8:msg_typei0e5:piecei-1ee"},
+		{"Not a dict", "i1e"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := UnmarshalMetadata([]byte(test.input)); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
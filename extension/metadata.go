@@ -0,0 +1,74 @@
+package extension
+
+import (
+	"fmt"
+
+	"github.com/ortymid/bencode"
+)
+
+// MetadataPieceSize is the size of every metadata piece but the last one.
+const MetadataPieceSize = 16 * 1024
+
+// Metadata message types, the "msg_type" key.
+const (
+	MetadataRequest = 0
+	MetadataData    = 1
+	MetadataReject  = 2
+)
+
+// MetadataMessage is a ut_metadata message. TotalSize and Data are only
+// set in data messages; Data holds the piece bytes which follow the
+// bencoded dict.
+type MetadataMessage struct {
+	Type      int    `bencode:"msg_type"`
+	Piece     int    `bencode:"piece"`
+	TotalSize int64  `bencode:"total_size,omitempty"`
+	Data      []byte `bencode:"-"`
+}
+
+// MarshalMetadata returns the payload of the message m.
+func MarshalMetadata(m *MetadataMessage) ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	b, err := bencode.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, m.Data...), nil
+}
+
+// UnmarshalMetadata parses the payload of a ut_metadata message.
+func UnmarshalMetadata(data []byte) (*MetadataMessage, error) {
+	m := &MetadataMessage{}
+	rest, err := bencode.UnmarshalPrefix(data, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		m.Data = rest
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *MetadataMessage) validate() error {
+	switch m.Type {
+	case MetadataRequest, MetadataReject:
+		if len(m.Data) > 0 {
+			return fmt.Errorf("extension: metadata message of type %d carries data", m.Type)
+		}
+	case MetadataData:
+		if len(m.Data) > MetadataPieceSize {
+			return fmt.Errorf("extension: metadata piece of %d bytes is too large", len(m.Data))
+		}
+	default:
+		return fmt.Errorf("extension: unknown metadata message type %d", m.Type)
+	}
+	if m.Piece < 0 {
+		return fmt.Errorf("extension: invalid metadata piece %d", m.Piece)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package extension
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/krpc"
+)
+
+// Peer flags of the added.f and added6.f strings.
+const (
+	FlagEncryption = 0x01
+	FlagSeed       = 0x02
+	FlagUTP        = 0x04
+	FlagHolepunch  = 0x08
+	FlagReachable  = 0x10
+)
+
+// PEXPeer is a peer added in a peer exchange message.
+type PEXPeer struct {
+	Addr  netip.AddrPort
+	Flags byte
+}
+
+// PEXMessage is a ut_pex message. The fields hold compact peer lists, the
+// flags strings have one byte per added peer.
+type PEXMessage struct {
+	Added    string `bencode:"added,omitempty"`
+	AddedF   string `bencode:"added.f,omitempty"`
+	Added6   string `bencode:"added6,omitempty"`
+	Added6F  string `bencode:"added6.f,omitempty"`
+	Dropped  string `bencode:"dropped,omitempty"`
+	Dropped6 string `bencode:"dropped6,omitempty"`
+}
+
+// NewPEXMessage returns a message with the given peers, split by address
+// family.
+func NewPEXMessage(added []PEXPeer, dropped []netip.AddrPort) *PEXMessage {
+	m := &PEXMessage{}
+	for _, p := range added {
+		if p.Addr.Addr().Unmap().Is4() {
+			m.Added += krpc.CompactPeer(p.Addr)
+			m.AddedF += string([]byte{p.Flags})
+		} else {
+			m.Added6 += krpc.CompactPeer(p.Addr)
+			m.Added6F += string([]byte{p.Flags})
+		}
+	}
+	for _, addr := range dropped {
+		if addr.Addr().Unmap().Is4() {
+			m.Dropped += krpc.CompactPeer(addr)
+		} else {
+			m.Dropped6 += krpc.CompactPeer(addr)
+		}
+	}
+	return m
+}
+
+// MarshalPEX returns the payload of the message m.
+func MarshalPEX(m *PEXMessage) ([]byte, error) {
+	return bencode.Marshal(m)
+}
+
+// UnmarshalPEX parses and validates the payload of a ut_pex message.
+func UnmarshalPEX(data []byte) (*PEXMessage, error) {
+	m := &PEXMessage{}
+	if err := bencode.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if _, err := m.AddedPeers(); err != nil {
+		return nil, err
+	}
+	if _, err := m.DroppedPeers(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddedPeers returns the added IPv4 and IPv6 peers. A missing flags string
+// means no flags.
+func (m *PEXMessage) AddedPeers() ([]PEXPeer, error) {
+	peers4, err := parsePEXPeers(m.Added, m.AddedF, false)
+	if err != nil {
+		return nil, err
+	}
+	peers6, err := parsePEXPeers(m.Added6, m.Added6F, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(peers4, peers6...), nil
+}
+
+// DroppedPeers returns the dropped IPv4 and IPv6 peers.
+func (m *PEXMessage) DroppedPeers() ([]netip.AddrPort, error) {
+	peers4, err := parsePEXPeers(m.Dropped, "", false)
+	if err != nil {
+		return nil, err
+	}
+	peers6, err := parsePEXPeers(m.Dropped6, "", true)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]netip.AddrPort, 0, len(peers4)+len(peers6))
+	for _, p := range append(peers4, peers6...) {
+		addrs = append(addrs, p.Addr)
+	}
+	return addrs, nil
+}
+
+func parsePEXPeers(s, flags string, ipv6 bool) ([]PEXPeer, error) {
+	size := 6
+	if ipv6 {
+		size = 18
+	}
+	if len(s)%size != 0 {
+		return nil, fmt.Errorf("extension: compact peers length %d is not a multiple of %d", len(s), size)
+	}
+	n := len(s) / size
+	if flags != "" && len(flags) != n {
+		return nil, fmt.Errorf("extension: %d flags for %d peers", len(flags), n)
+	}
+	peers := make([]PEXPeer, n)
+	for i := range peers {
+		addr, err := krpc.ParseCompactPeer(s[i*size : (i+1)*size])
+		if err != nil {
+			return nil, err
+		}
+		peers[i].Addr = addr
+		if flags != "" {
+			peers[i].Flags = flags[i]
+		}
+	}
+	return peers, nil
+}
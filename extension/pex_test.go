@@ -0,0 +1,60 @@
+package extension
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestPEXMessage(t *testing.T) {
+	added := []PEXPeer{
+		{netip.MustParseAddrPort("1.2.3.4:6881"), FlagSeed | FlagReachable},
+		{netip.MustParseAddrPort("[2001:db8::1]:80"), FlagUTP},
+		{netip.MustParseAddrPort("5.6.7.8:1"), 0x80},
+	}
+	dropped := []netip.AddrPort{netip.MustParseAddrPort("9.9.9.9:9")}
+
+	data, err := MarshalPEX(NewPEXMessage(added, dropped))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := "d5:added12:\x01\x02\x03\x04\x1a\xe1\x05\x06\x07\x08\x00\x017:added.f2:\x12\x80" +
+		"6:added618:\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x508:added6.f1:\x04" +
+		"7:dropped6:\x09\x09\x09\x09\x00\x09e"
+	if string(data) != want {
+		t.Errorf("\ngot: %q \nwant: %q", data, want)
+	}
+
+	m, err := UnmarshalPEX(data)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	gotAdded, _ := m.AddedPeers()
+	wantAdded := []PEXPeer{added[0], added[2], added[1]} // IPv4 first
+	if !reflect.DeepEqual(gotAdded, wantAdded) {
+		t.Errorf("\ngot: %v \nwant: %v", gotAdded, wantAdded)
+	}
+	gotDropped, _ := m.DroppedPeers()
+	if !reflect.DeepEqual(gotDropped, dropped) {
+		t.Errorf("\ngot: %v \nwant: %v", gotDropped, dropped)
+	}
+}
+
+func TestPEXMessageError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Bad length", "d5:added5:\x01\x02\x03\x04\x1ae"},
+		{"Flags count", "d5:added6:\x01\x02\x03\x04\x1a\xe17:added.f2:\x00\x00e"},
+		{"Bad dropped6", "d8:dropped66:\x01\x02\x03\x04\x1a\xe1e"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := UnmarshalPEX([]byte(test.input)); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// BinaryEncoding selects how strings which are not valid UTF-8 are
+// represented in JSON.
+type BinaryEncoding int
+
+const (
+	// BinaryTagged writes binary strings as {"$bytes":"<base64>"} objects.
+	// It is the only lossless strategy and the default one.
+	BinaryTagged BinaryEncoding = iota
+	// BinaryHex writes binary strings as plain hex strings.
+	BinaryHex
+	// BinaryBase64 writes binary strings as plain standard base64 strings.
+	BinaryBase64
+)
+
+const (
+	jsonBytesKey = "$bytes"
+	jsonDictKey  = "$dict"
+)
+
+// JSONOptions configures the conversion between Value and JSON.
+type JSONOptions struct {
+	// Binary is the strategy for strings which are not valid UTF-8.
+	Binary BinaryEncoding
+	// Indent, if not empty, is used to indent nested JSON values.
+	Indent string
+}
+
+// ToJSON converts v to JSON. Dict keys keep their order. With BinaryTagged
+// the conversion is lossless and FromJSON restores the original Value; with
+// BinaryHex and BinaryBase64 binary strings come back as text.
+func ToJSON(v Value, opts JSONOptions) ([]byte, error) {
+	b, err := appendJSON(nil, v, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Indent == "" {
+		return b, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", opts.Indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func appendJSON(b []byte, v Value, opts JSONOptions) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case Int:
+		b = strconv.AppendInt(b, int64(v), 10)
+	case String:
+		b = appendJSONBencodeString(b, v, opts)
+	case List:
+		b = append(b, '[')
+		for i, item := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = appendJSON(b, item, opts); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, ']')
+	case *Dict:
+		if opts.Binary == BinaryTagged && needsDictTag(v) {
+			return appendJSONTaggedDict(b, v, opts)
+		}
+		b = append(b, '{')
+		for i, key := range v.keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONBencodeString(b, key, opts)
+			b = append(b, ':')
+			if b, err = appendJSON(b, v.m[key], opts); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, '}')
+	default:
+		return nil, fmt.Errorf("bencode: cannot convert %T to JSON", v)
+	}
+	return b, nil
+}
+
+// needsDictTag reports whether d can not be written as a plain JSON object
+// without loss: it has binary keys or it looks like a tagged value itself.
+func needsDictTag(d *Dict) bool {
+	if len(d.keys) == 1 && (d.keys[0] == jsonBytesKey || d.keys[0] == jsonDictKey) {
+		return true
+	}
+	for _, key := range d.keys {
+		if !utf8.ValidString(string(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONTaggedDict writes d as {"$dict":[[key,value],...]}.
+func appendJSONTaggedDict(b []byte, d *Dict, opts JSONOptions) ([]byte, error) {
+	var err error
+	b = append(b, `{"`+jsonDictKey+`":[`...)
+	for i, key := range d.keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '[')
+		b = appendJSONBencodeString(b, key, opts)
+		b = append(b, ',')
+		if b, err = appendJSON(b, d.m[key], opts); err != nil {
+			return nil, err
+		}
+		b = append(b, ']')
+	}
+	return append(b, ']', '}'), nil
+}
+
+func appendJSONBencodeString(b []byte, s String, opts JSONOptions) []byte {
+	if utf8.ValidString(string(s)) {
+		return appendJSONString(b, string(s))
+	}
+	switch opts.Binary {
+	case BinaryHex:
+		return appendJSONString(b, hex.EncodeToString([]byte(s)))
+	case BinaryBase64:
+		return appendJSONString(b, base64.StdEncoding.EncodeToString([]byte(s)))
+	}
+	b = append(b, `{"`+jsonBytesKey+`":`...)
+	b = appendJSONString(b, base64.StdEncoding.EncodeToString([]byte(s)))
+	return append(b, '}')
+}
+
+// appendJSONString appends s as a JSON string. Unlike encoding/json it does
+// not escape HTML characters, so the output stays readable.
+func appendJSONString(b []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			b = append(b, '\\', 'u')
+			for shift := 12; shift >= 0; shift -= 4 {
+				b = append(b, hexDigits[r>>uint(shift)&0xf])
+			}
+		default:
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	return append(b, '"')
+}
+
+// FromJSON converts JSON produced by ToJSON back to a Value. Objects become
+// dicts with the keys in the order of appearance. Tagged binary strings are
+// recognized regardless of opts. Floats, booleans and nulls have no bencode
+// representation and are reported as errors.
+func FromJSON(data []byte, opts JSONOptions) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := fromJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("bencode: unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func fromJSONValue(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("bencode: cannot read JSON: %w", err)
+	}
+	switch tok := tok.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(string(tok), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bencode: cannot convert JSON number %s to integer", tok)
+		}
+		return Int(i), nil
+	case string:
+		return String(tok), nil
+	case json.Delim:
+		switch tok {
+		case '[':
+			list := List{}
+			for dec.More() {
+				item, err := fromJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("bencode: cannot read JSON: %w", err)
+			}
+			return list, nil
+		case '{':
+			return fromJSONObject(dec)
+		}
+	}
+	return nil, fmt.Errorf("bencode: JSON value %v has no bencode representation", tok)
+}
+
+func fromJSONObject(dec *json.Decoder) (Value, error) {
+	dict := NewDict()
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("bencode: cannot read JSON: %w", err)
+		}
+		key := tok.(string) // object keys are always strings
+		val, err := fromJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(String(key), val)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("bencode: cannot read JSON: %w", err)
+	}
+
+	// unwrap tagged values
+	if len(dict.keys) != 1 {
+		return dict, nil
+	}
+	switch val := dict.m[dict.keys[0]]; dict.keys[0] {
+	case jsonBytesKey:
+		s, ok := val.(String)
+		if !ok {
+			return nil, fmt.Errorf("bencode: %s value must be a base64 string", jsonBytesKey)
+		}
+		b, err := base64.StdEncoding.DecodeString(string(s))
+		if err != nil {
+			return nil, fmt.Errorf("bencode: cannot decode %s value: %w", jsonBytesKey, err)
+		}
+		return String(b), nil
+	case jsonDictKey:
+		return fromJSONTaggedDict(val)
+	}
+	return dict, nil
+}
+
+func fromJSONTaggedDict(val Value) (*Dict, error) {
+	pairs, ok := val.(List)
+	if !ok {
+		return nil, fmt.Errorf("bencode: %s value must be a list of key-value pairs", jsonDictKey)
+	}
+	dict := NewDict()
+	for _, pair := range pairs {
+		kv, ok := pair.(List)
+		if !ok || len(kv) != 2 {
+			return nil, fmt.Errorf("bencode: %s value must be a list of key-value pairs", jsonDictKey)
+		}
+		key, ok := kv[0].(String)
+		if !ok {
+			return nil, fmt.Errorf("bencode: %s key must be a string", jsonDictKey)
+		}
+		dict.Set(key, kv[1])
+	}
+	return dict, nil
+}
@@ -0,0 +1,92 @@
+package bencode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		val    Value
+		binary BinaryEncoding
+		want   string
+	}{
+		{"Int", Int(-42), BinaryTagged, `-42`},
+		{"String/Text", String("spam \"eggs\"\n"), BinaryTagged, `"spam \"eggs\"\n"`},
+		{"String/Tagged", String("\xde\xad\xbe\xef"), BinaryTagged, `{"$bytes":"3q2+7w=="}`},
+		{"String/Hex", String("\xde\xad\xbe\xef"), BinaryHex, `"deadbeef"`},
+		{"String/Base64", String("\xde\xad\xbe\xef"), BinaryBase64, `"3q2+7w=="`},
+		{"List", List{String("spam"), Int(42)}, BinaryTagged, `["spam",42]`},
+		{"Dict/Order", NewDict([]DictItem{{String("z"), Int(1)}, {String("a"), Int(2)}}...), BinaryTagged, `{"z":1,"a":2}`},
+		{"Dict/Binary key", NewDict([]DictItem{{String("\xff"), Int(1)}}...), BinaryTagged, `{"$dict":[[{"$bytes":"/w=="},1]]}`},
+		{"Dict/Binary key hex", NewDict([]DictItem{{String("\xff"), Int(1)}}...), BinaryHex, `{"ff":1}`},
+		{"Dict/Looks tagged", NewDict([]DictItem{{String("$bytes"), String("spam")}}...), BinaryTagged, `{"$dict":[["$bytes","spam"]]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ToJSON(test.val, JSONOptions{Binary: test.binary})
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if string(got) != test.want {
+				t.Error("got:", string(got), "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundtrip(t *testing.T) {
+	tests := []struct {
+		name string
+		val  Value
+	}{
+		{"Int", Int(1 << 62)},
+		{"String/Binary", String("\x00\x01\xfe\xff")},
+		{"List/Empty", List{}},
+		{"List/Nested", List{List{String("spam")}, Int(0)}},
+		{"Dict/Empty", NewDict()},
+		{"Dict/Binary keys", NewDict([]DictItem{{String("\x00\xff"), String("a")}, {String("b"), String("\xff")}}...)},
+		{"Dict/Looks tagged", NewDict([]DictItem{{String("$dict"), List{}}}...)},
+		{"Dict/Nested", NewDict([]DictItem{{String("spam"), NewDict([]DictItem{{String("eggs"), Int(1)}}...)}}...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := ToJSON(test.val, JSONOptions{Indent: "  "})
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			got, err := FromJSON(data, JSONOptions{})
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.val) {
+				t.Errorf("\ngot: %v \nwant: %v", got, test.val)
+			}
+		})
+	}
+}
+
+func TestFromJSONError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Float", `1.5`},
+		{"Bool", `true`},
+		{"Null", `null`},
+		{"Trailing data", `1 2`},
+		{"Bad bytes", `{"$bytes":"!"}`},
+		{"Bad dict", `{"$dict":[["a"]]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := FromJSON([]byte(test.input), JSONOptions{}); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
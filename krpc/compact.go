@@ -0,0 +1,120 @@
+package krpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// IDSize is the length of a node ID.
+const IDSize = 20
+
+// ID is a node ID, which shares its space with info-hashes.
+type ID [IDSize]byte
+
+// NodeInfo is a node ID along with its address.
+type NodeInfo struct {
+	ID   ID
+	Addr netip.AddrPort
+}
+
+// ParseCompactNodes parses compact node info: 26-byte entries of an ID, an
+// IPv4 address and a port, or 38-byte entries with IPv6 addresses if ipv6
+// is set (BEP 32).
+func ParseCompactNodes(s string, ipv6 bool) ([]NodeInfo, error) {
+	size := IDSize + compactPeerSize(ipv6)
+	if len(s)%size != 0 {
+		return nil, fmt.Errorf("krpc: compact nodes length %d is not a multiple of %d", len(s), size)
+	}
+	nodes := make([]NodeInfo, 0, len(s)/size)
+	for i := 0; i < len(s); i += size {
+		var n NodeInfo
+		copy(n.ID[:], s[i:])
+		n.Addr, _ = ParseCompactPeer(s[i+IDSize : i+size])
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// CompactNodes returns nodes in the compact node info format. The nodes
+// must all have IPv4 or all have IPv6 addresses.
+func CompactNodes(nodes []NodeInfo) string {
+	var b []byte
+	for _, n := range nodes {
+		b = append(b, n.ID[:]...)
+		b = appendCompactPeer(b, n.Addr)
+	}
+	return string(b)
+}
+
+// ParseCompactPeer parses compact peer info: a 4-byte IPv4 or a 16-byte IPv6
+// address followed by a 2-byte port, in network byte order.
+func ParseCompactPeer(s string) (netip.AddrPort, error) {
+	var addr netip.Addr
+	switch len(s) {
+	case compactPeerSize(false):
+		var ip [4]byte
+		copy(ip[:], s)
+		addr = netip.AddrFrom4(ip)
+	case compactPeerSize(true):
+		var ip [16]byte
+		copy(ip[:], s)
+		addr = netip.AddrFrom16(ip)
+	default:
+		return netip.AddrPort{}, fmt.Errorf("krpc: invalid compact peer length %d", len(s))
+	}
+	port := binary.BigEndian.Uint16([]byte(s[len(s)-2:]))
+	return netip.AddrPortFrom(addr, port), nil
+}
+
+// CompactPeer returns addr in the compact peer info format.
+func CompactPeer(addr netip.AddrPort) string {
+	return string(appendCompactPeer(nil, addr))
+}
+
+func appendCompactPeer(b []byte, addr netip.AddrPort) []byte {
+	ip := addr.Addr().Unmap()
+	if ip.Is4() {
+		a := ip.As4()
+		b = append(b, a[:]...)
+	} else {
+		a := ip.As16()
+		b = append(b, a[:]...)
+	}
+	var port [2]byte
+	binary.BigEndian.PutUint16(port[:], addr.Port())
+	return append(b, port[:]...)
+}
+
+func compactPeerSize(ipv6 bool) int {
+	if ipv6 {
+		return 18
+	}
+	return 6
+}
+
+// Peers parses the compact peer info in the values of a get_peers response.
+func (r *Return) Peers() ([]netip.AddrPort, error) {
+	peers := make([]netip.AddrPort, 0, len(r.Values))
+	for _, v := range r.Values {
+		p, err := ParseCompactPeer(v)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+// NodeInfos parses the IPv4 and IPv6 nodes of a response.
+func (r *Return) NodeInfos() ([]NodeInfo, error) {
+	nodes, err := ParseCompactNodes(r.Nodes, false)
+	if err != nil {
+		return nil, err
+	}
+	nodes6, err := ParseCompactNodes(r.Nodes6, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(nodes, nodes6...), nil
+}
@@ -0,0 +1,71 @@
+package krpc
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestCompactPeer(t *testing.T) {
+	tests := []struct {
+		addr netip.AddrPort
+		want string
+	}{
+		{netip.MustParseAddrPort("1.2.3.4:6881"), "\x01\x02\x03\x04\x1a\xe1"},
+		{netip.MustParseAddrPort("[::ffff:1.2.3.4]:6881"), "\x01\x02\x03\x04\x1a\xe1"},
+		{netip.MustParseAddrPort("[2001:db8::1]:80"), "\x20\x01\x0d\xb8" + string(make([]byte, 11)) + "\x01\x00\x50"},
+	}
+	for _, test := range tests {
+		got := CompactPeer(test.addr)
+		if got != test.want {
+			t.Errorf("got: %q want: %q", got, test.want)
+		}
+		back, err := ParseCompactPeer(got)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if back != netip.AddrPortFrom(test.addr.Addr().Unmap(), test.addr.Port()) {
+			t.Error("got:", back, "want:", test.addr)
+		}
+	}
+	if _, err := ParseCompactPeer("12345"); err == nil {
+		t.Error("no error for a short peer")
+	}
+}
+
+func TestCompactNodes(t *testing.T) {
+	nodes := []NodeInfo{
+		{ID{1}, netip.MustParseAddrPort("1.2.3.4:1")},
+		{ID{2}, netip.MustParseAddrPort("5.6.7.8:2")},
+	}
+	s := CompactNodes(nodes)
+	if len(s) != 2*26 {
+		t.Fatal("got length:", len(s), "want:", 2*26)
+	}
+
+	r := &Return{Nodes: s, Nodes6: CompactNodes([]NodeInfo{{ID{3}, netip.MustParseAddrPort("[::1]:3")}})}
+	got, err := r.NodeInfos()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := append(nodes, NodeInfo{ID{3}, netip.MustParseAddrPort("[::1]:3")})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %v \nwant: %v", got, want)
+	}
+
+	if _, err := ParseCompactNodes(s[:25], false); err == nil {
+		t.Error("no error for a truncated node")
+	}
+}
+
+func TestReturnPeers(t *testing.T) {
+	r := &Return{Values: []string{"axje.u", "idhtnm"}}
+	got, err := r.Peers()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := []netip.AddrPort{netip.MustParseAddrPort("97.120.106.101:11893"), netip.MustParseAddrPort("105.100.104.116:28269")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %v \nwant: %v", got, want)
+	}
+}
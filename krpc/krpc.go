@@ -0,0 +1,166 @@
+// Package krpc provides the messages of the DHT protocol (BEP 5), which are
+// bencoded dicts sent over UDP.
+package krpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ortymid/bencode"
+)
+
+//go:generate go run github.com/ortymid/bencode/cmd/bencodegen -type Message,Args,Return $GOFILE
+
+// Message types, the "y" key.
+const (
+	TypeQuery    = "q"
+	TypeResponse = "r"
+	TypeError    = "e"
+)
+
+// Query methods, the "q" key.
+const (
+	MethodPing         = "ping"
+	MethodFindNode     = "find_node"
+	MethodGetPeers     = "get_peers"
+	MethodAnnouncePeer = "announce_peer"
+)
+
+// Message is a KRPC message envelope. Exactly one of A, R and E is set,
+// depending on the type.
+type Message struct {
+	T string  `bencode:"t"`
+	Y string  `bencode:"y"`
+	Q string  `bencode:"q,omitempty"`
+	A *Args   `bencode:"a,omitempty"`
+	R *Return `bencode:"r,omitempty"`
+	E *Error  `bencode:"e,omitempty"`
+	// V is the client version (BEP 20).
+	V string `bencode:"v,omitempty"`
+}
+
+// Args are the arguments of a query. ID is always set, the rest depends on
+// the method.
+type Args struct {
+	ID          string `bencode:"id"`
+	Target      string `bencode:"target,omitempty"`       // find_node
+	InfoHash    string `bencode:"info_hash,omitempty"`    // get_peers, announce_peer
+	Port        int    `bencode:"port,omitempty"`         // announce_peer
+	ImpliedPort int    `bencode:"implied_port,omitempty"` // announce_peer
+	Token       string `bencode:"token,omitempty"`        // announce_peer
+}
+
+// Return holds the return values of a response. Nodes and Nodes6 are in the
+// compact node info format, Values in the compact peer info format.
+type Return struct {
+	ID     string   `bencode:"id"`
+	Nodes  string   `bencode:"nodes,omitempty"`
+	Nodes6 string   `bencode:"nodes6,omitempty"`
+	Token  string   `bencode:"token,omitempty"`
+	Values []string `bencode:"values,omitempty"`
+}
+
+// Error codes.
+const (
+	ErrGeneric       = 201
+	ErrServer        = 202
+	ErrProtocol      = 203
+	ErrMethodUnknown = 204
+)
+
+// Error is a KRPC error. It is encoded as a list of the code and the
+// message.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("krpc: error %d: %s", e.Code, e.Message) }
+
+// MarshalBencode implements bencode.Marshaler.
+func (e Error) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal([]interface{}{e.Code, e.Message})
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (e *Error) UnmarshalBencode(data []byte) error {
+	var l []interface{}
+	if err := bencode.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	if len(l) != 2 {
+		return fmt.Errorf("krpc: error has %d items, want 2", len(l))
+	}
+	code, ok := l[0].(int64)
+	if !ok {
+		return fmt.Errorf("krpc: error code is %T, want an integer", l[0])
+	}
+	msg, ok := l[1].(string)
+	if !ok {
+		return fmt.Errorf("krpc: error message is %T, want a string", l[1])
+	}
+	e.Code, e.Message = int(code), msg
+	return nil
+}
+
+// NewQuery returns a query message.
+func NewQuery(t, method string, args Args) *Message {
+	return &Message{T: t, Y: TypeQuery, Q: method, A: &args}
+}
+
+// NewResponse returns a response message.
+func NewResponse(t string, r Return) *Message {
+	return &Message{T: t, Y: TypeResponse, R: &r}
+}
+
+// NewError returns an error message.
+func NewError(t string, code int, msg string) *Message {
+	return &Message{T: t, Y: TypeError, E: &Error{code, msg}}
+}
+
+// Marshal returns the bencoding of m.
+func Marshal(m *Message) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m.MarshalBencode()
+}
+
+// Unmarshal parses and validates a message.
+func Unmarshal(data []byte) (*Message, error) {
+	m := &Message{}
+	if err := m.UnmarshalBencode(data); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks that the envelope matches its type.
+func (m *Message) Validate() error {
+	switch m.Y {
+	case TypeQuery:
+		if m.Q == "" || m.A == nil {
+			return errors.New("krpc: query without method or arguments")
+		}
+		if len(m.A.ID) != IDSize {
+			return fmt.Errorf("krpc: invalid node id length %d", len(m.A.ID))
+		}
+	case TypeResponse:
+		if m.R == nil {
+			return errors.New("krpc: response without return values")
+		}
+		if len(m.R.ID) != IDSize {
+			return fmt.Errorf("krpc: invalid node id length %d", len(m.R.ID))
+		}
+	case TypeError:
+		if m.E == nil {
+			return errors.New("krpc: error without code and message")
+		}
+	default:
+		return fmt.Errorf("krpc: unknown message type %q", m.Y)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+// Code generated by bencodegen; DO NOT EDIT.
+
+package krpc
+
+import (
+	"fmt"
+
+	"github.com/ortymid/bencode"
+)
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Message) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Message) appendBencode(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, 'd')
+	if x.A != nil {
+		b = append(b, "1:a"...)
+		if b, err = (*x.A).appendBencode(b); err != nil {
+			return nil, err
+		}
+	}
+	if x.E != nil {
+		b = append(b, "1:e"...)
+		raw1, err := bencode.Marshal((*x.E))
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, raw1...)
+	}
+	if len(x.Q) != 0 {
+		b = append(b, "1:q"...)
+		b = bencode.String(x.Q).AppendBencode(b)
+	}
+	if x.R != nil {
+		b = append(b, "1:r"...)
+		if b, err = (*x.R).appendBencode(b); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, "1:t"...)
+	b = bencode.String(x.T).AppendBencode(b)
+	if len(x.V) != 0 {
+		b = append(b, "1:v"...)
+		b = bencode.String(x.V).AppendBencode(b)
+	}
+	b = append(b, "1:y"...)
+	b = bencode.String(x.Y).AppendBencode(b)
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Message) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Message) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "a":
+			if x.A == nil {
+				x.A = new(Args)
+			}
+			if err := (*x.A).decodeBencode(s); err != nil {
+				return err
+			}
+		case "e":
+			if x.E == nil {
+				x.E = new(Error)
+			}
+			raw2, err := s.ReadRaw()
+			if err != nil {
+				return err
+			}
+			if err := bencode.Unmarshal(raw2, &(*x.E)); err != nil {
+				return err
+			}
+		case "q":
+			v3, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Q = string(v3)
+		case "r":
+			if x.R == nil {
+				x.R = new(Return)
+			}
+			if err := (*x.R).decodeBencode(s); err != nil {
+				return err
+			}
+		case "t":
+			v4, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.T = string(v4)
+		case "v":
+			v5, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.V = string(v5)
+		case "y":
+			v6, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Y = string(v6)
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Args) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Args) appendBencode(b []byte) ([]byte, error) {
+	b = append(b, 'd')
+	b = append(b, "2:id"...)
+	b = bencode.String(x.ID).AppendBencode(b)
+	if x.ImpliedPort != 0 {
+		b = append(b, "12:implied_port"...)
+		b = bencode.Int(x.ImpliedPort).AppendBencode(b)
+	}
+	if len(x.InfoHash) != 0 {
+		b = append(b, "9:info_hash"...)
+		b = bencode.String(x.InfoHash).AppendBencode(b)
+	}
+	if x.Port != 0 {
+		b = append(b, "4:port"...)
+		b = bencode.Int(x.Port).AppendBencode(b)
+	}
+	if len(x.Target) != 0 {
+		b = append(b, "6:target"...)
+		b = bencode.String(x.Target).AppendBencode(b)
+	}
+	if len(x.Token) != 0 {
+		b = append(b, "5:token"...)
+		b = bencode.String(x.Token).AppendBencode(b)
+	}
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Args) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Args) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "id":
+			v7, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.ID = string(v7)
+		case "implied_port":
+			v8, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if int64(int(v8)) != v8 {
+				return fmt.Errorf("bencode: %d overflows int", v8)
+			}
+			x.ImpliedPort = int(v8)
+		case "info_hash":
+			v9, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.InfoHash = string(v9)
+		case "port":
+			v10, err := s.ReadInt()
+			if err != nil {
+				return err
+			}
+			if int64(int(v10)) != v10 {
+				return fmt.Errorf("bencode: %d overflows int", v10)
+			}
+			x.Port = int(v10)
+		case "target":
+			v11, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Target = string(v11)
+		case "token":
+			v12, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Token = string(v12)
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (x Return) MarshalBencode() ([]byte, error) {
+	return x.appendBencode(nil)
+}
+
+func (x Return) appendBencode(b []byte) ([]byte, error) {
+	b = append(b, 'd')
+	b = append(b, "2:id"...)
+	b = bencode.String(x.ID).AppendBencode(b)
+	if len(x.Nodes) != 0 {
+		b = append(b, "5:nodes"...)
+		b = bencode.String(x.Nodes).AppendBencode(b)
+	}
+	if len(x.Nodes6) != 0 {
+		b = append(b, "6:nodes6"...)
+		b = bencode.String(x.Nodes6).AppendBencode(b)
+	}
+	if len(x.Token) != 0 {
+		b = append(b, "5:token"...)
+		b = bencode.String(x.Token).AppendBencode(b)
+	}
+	if len(x.Values) != 0 {
+		b = append(b, "6:values"...)
+		b = append(b, 'l')
+		for _, v13 := range x.Values {
+			b = bencode.String(v13).AppendBencode(b)
+		}
+		b = append(b, 'e')
+	}
+	return append(b, 'e'), nil
+}
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (x *Return) UnmarshalBencode(data []byte) error {
+	s := bencode.NewScanner(data)
+	if err := x.decodeBencode(s); err != nil {
+		return err
+	}
+	return s.Finish()
+}
+
+func (x *Return) decodeBencode(s *bencode.Scanner) error {
+	if err := s.ReadDictStart(); err != nil {
+		return err
+	}
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "id":
+			v14, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.ID = string(v14)
+		case "nodes":
+			v15, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Nodes = string(v15)
+		case "nodes6":
+			v16, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Nodes6 = string(v16)
+		case "token":
+			v17, err := s.ReadString()
+			if err != nil {
+				return err
+			}
+			x.Token = string(v17)
+		case "values":
+			if err := s.ReadListStart(); err != nil {
+				return err
+			}
+			x.Values = x.Values[:0]
+			for s.More() {
+				var v18 string
+				v19, err := s.ReadString()
+				if err != nil {
+					return err
+				}
+				v18 = string(v19)
+				x.Values = append(x.Values, v18)
+			}
+			if err := s.ReadEnd(); err != nil {
+				return err
+			}
+		default:
+			if err := s.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.ReadEnd()
+}
@@ -0,0 +1,96 @@
+package krpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	const (
+		id       = "abcdefghij0123456789"
+		infoHash = "mnopqrstuvwxyz123456"
+	)
+	tests := []struct {
+		name  string
+		input string
+		want  *Message
+	}{
+		{
+			"Ping",
+			"d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe",
+			NewQuery("aa", MethodPing, Args{ID: id}),
+		},
+		{
+			"Ping response",
+			"d1:rd2:id20:mnopqrstuvwxyz123456e1:t2:aa1:y1:re",
+			NewResponse("aa", Return{ID: infoHash}),
+		},
+		{
+			"Error",
+			"d1:eli201e23:A Generic Error Ocurrede1:t2:aa1:y1:ee",
+			NewError("aa", ErrGeneric, "A Generic Error Ocurred"),
+		},
+		{
+			"Find node",
+			"d1:ad2:id20:abcdefghij01234567896:target20:mnopqrstuvwxyz123456e1:q9:find_node1:t2:aa1:y1:qe",
+			NewQuery("aa", MethodFindNode, Args{ID: id, Target: infoHash}),
+		},
+		{
+			"Get peers",
+			"d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz123456e1:q9:get_peers1:t2:aa1:y1:qe",
+			NewQuery("aa", MethodGetPeers, Args{ID: id, InfoHash: infoHash}),
+		},
+		{
+			"Get peers response",
+			"d1:rd2:id20:abcdefghij01234567895:token8:aoeusnth6:valuesl6:axje.u6:idhtnmee1:t2:aa1:y1:re",
+			NewResponse("aa", Return{ID: id, Token: "aoeusnth", Values: []string{"axje.u", "idhtnm"}}),
+		},
+		{
+			"Announce peer",
+			"d1:ad2:id20:abcdefghij012345678912:implied_porti1e9:info_hash20:mnopqrstuvwxyz1234564:porti6881e5:token8:aoeusnthe1:q13:announce_peer1:t2:aa1:y1:qe",
+			NewQuery("aa", MethodAnnouncePeer, Args{ID: id, InfoHash: infoHash, Port: 6881, ImpliedPort: 1, Token: "aoeusnth"}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Unmarshal([]byte(test.input))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", got, test.want)
+			}
+
+			data, err := Marshal(got)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(data) != test.input {
+				t.Errorf("\ngot: %s \nwant: %s", data, test.input)
+			}
+		})
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Unknown type", "d1:t2:aa1:y1:xe"},
+		{"Query without args", "d1:q4:ping1:t2:aa1:y1:qe"},
+		{"Short id", "d1:ad2:id3:abce1:q4:ping1:t2:aa1:y1:qe"},
+		{"Response without return", "d1:t2:aa1:y1:re"},
+		{"Error without list", "d1:t2:aa1:y1:ee"},
+		{"Error items", "d1:eli201ee1:t2:aa1:y1:ee"},
+		{"Error types", "d1:el3:abci201ee1:t2:aa1:y1:ee"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(test.input)); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Package magnet parses and builds magnet links (BEP 9), including v2
+// info-hashes and file selection (BEP 53).
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ortymid/bencode/metainfo"
+)
+
+const (
+	prefixV1 = "urn:btih:"
+	prefixV2 = "urn:btmh:"
+	// multihashV2 is the multihash prefix of a SHA-256 digest.
+	multihashV2 = "1220"
+)
+
+// Magnet is a parsed magnet link. At least one of the info-hashes is set.
+type Magnet struct {
+	V1          *metainfo.HashV1
+	V2          *metainfo.HashV2
+	DisplayName string   // dn
+	Trackers    []string // tr
+	WebSeeds    []string // ws
+	Peers       []string // x.pe, host:port
+	// SelectOnly lists the indices of the files to download (so).
+	SelectOnly []Range
+}
+
+// Range is an inclusive range of file indices.
+type Range struct {
+	First, Last int
+}
+
+// Parse parses a magnet link.
+func Parse(uri string) (*Magnet, error) {
+	const scheme = "magnet:?"
+	if !strings.HasPrefix(uri, scheme) {
+		return nil, errors.New("magnet: not a magnet link")
+	}
+
+	// parse parameters by hand to keep the order of trackers
+	m := &Magnet{}
+	for _, param := range strings.Split(uri[len(scheme):], "&") {
+		if param == "" {
+			continue
+		}
+		key, value := param, ""
+		if i := strings.Index(param, "="); i != -1 {
+			key, value = param[:i], param[i+1:]
+		}
+		value, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("magnet: %w", err)
+		}
+		// clients may number repeated parameters, as in tr.1
+		if i := strings.LastIndex(key, "."); i != -1 && key != "x.pe" {
+			if _, err := strconv.Atoi(key[i+1:]); err == nil {
+				key = key[:i]
+			}
+		}
+		if err := m.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if m.V1 == nil && m.V2 == nil {
+		return nil, errors.New("magnet: no info-hash")
+	}
+	return m, nil
+}
+
+func (m *Magnet) set(key, value string) error {
+	switch key {
+	case "xt":
+		return m.setExactTopic(value)
+	case "dn":
+		m.DisplayName = value
+	case "tr":
+		m.Trackers = append(m.Trackers, value)
+	case "ws":
+		m.WebSeeds = append(m.WebSeeds, value)
+	case "x.pe":
+		m.Peers = append(m.Peers, value)
+	case "so":
+		so, err := parseRanges(value)
+		if err != nil {
+			return err
+		}
+		m.SelectOnly = append(m.SelectOnly, so...)
+	}
+	return nil
+}
+
+func (m *Magnet) setExactTopic(xt string) error {
+	switch {
+	case strings.HasPrefix(xt, prefixV1):
+		h, err := parseHashV1(xt[len(prefixV1):])
+		if err != nil {
+			return err
+		}
+		m.V1 = &h
+	case strings.HasPrefix(xt, prefixV2):
+		s := xt[len(prefixV2):]
+		if !strings.HasPrefix(s, multihashV2) {
+			return fmt.Errorf("magnet: unsupported multihash %q", s)
+		}
+		var h metainfo.HashV2
+		if err := decodeHex(h[:], s[len(multihashV2):]); err != nil {
+			return err
+		}
+		m.V2 = &h
+	}
+	return nil
+}
+
+// parseHashV1 parses a v1 info-hash in hex or base32.
+func parseHashV1(s string) (metainfo.HashV1, error) {
+	var h metainfo.HashV1
+	switch len(s) {
+	case 2 * len(h):
+		return h, decodeHex(h[:], s)
+	case 32:
+		b, err := base32.StdEncoding.DecodeString(strings.ToUpper(s))
+		if err != nil {
+			return h, fmt.Errorf("magnet: invalid base32 info-hash %q", s)
+		}
+		copy(h[:], b)
+		return h, nil
+	}
+	return h, fmt.Errorf("magnet: invalid info-hash %q", s)
+}
+
+func decodeHex(dst []byte, s string) error {
+	if len(s) != 2*len(dst) {
+		return fmt.Errorf("magnet: invalid info-hash %q", s)
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return fmt.Errorf("magnet: invalid info-hash %q", s)
+	}
+	return nil
+}
+
+// parseRanges parses a comma separated list of indices and ranges, such as
+// "0,2,4-6".
+func parseRanges(s string) ([]Range, error) {
+	var ranges []Range
+	for _, part := range strings.Split(s, ",") {
+		first, last := part, part
+		if i := strings.Index(part, "-"); i != -1 {
+			first, last = part[:i], part[i+1:]
+		}
+		a, err1 := strconv.Atoi(first)
+		b, err2 := strconv.Atoi(last)
+		if err1 != nil || err2 != nil || a < 0 || b < a {
+			return nil, fmt.Errorf("magnet: invalid file selection %q", s)
+		}
+		ranges = append(ranges, Range{a, b})
+	}
+	return ranges, nil
+}
+
+// String returns the magnet link. Info-hashes are written in hex.
+func (m *Magnet) String() string {
+	var params []string
+	if m.V1 != nil {
+		params = append(params, "xt="+prefixV1+m.V1.String())
+	}
+	if m.V2 != nil {
+		params = append(params, "xt="+prefixV2+multihashV2+m.V2.String())
+	}
+	if m.DisplayName != "" {
+		params = append(params, "dn="+url.QueryEscape(m.DisplayName))
+	}
+	for _, tr := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tr))
+	}
+	for _, ws := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(ws))
+	}
+	for _, pe := range m.Peers {
+		params = append(params, "x.pe="+url.QueryEscape(pe))
+	}
+	if len(m.SelectOnly) > 0 {
+		so := make([]string, len(m.SelectOnly))
+		for i, r := range m.SelectOnly {
+			so[i] = strconv.Itoa(r.First)
+			if r.Last != r.First {
+				so[i] += "-" + strconv.Itoa(r.Last)
+			}
+		}
+		params = append(params, "so="+strings.Join(so, ","))
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// FromMetaInfo builds a magnet link of a torrent with its info-hashes, name
+// and trackers.
+func FromMetaInfo(mi *metainfo.MetaInfo) (*Magnet, error) {
+	m := &Magnet{DisplayName: mi.Info.Name}
+	if mi.Info.HasV1() {
+		h, err := mi.InfoHashV1()
+		if err != nil {
+			return nil, err
+		}
+		m.V1 = &h
+	}
+	if mi.Info.HasV2() {
+		h, err := mi.InfoHashV2()
+		if err != nil {
+			return nil, err
+		}
+		m.V2 = &h
+	}
+
+	seen := make(map[string]bool)
+	add := func(tr string) {
+		if tr != "" && !seen[tr] {
+			seen[tr] = true
+			m.Trackers = append(m.Trackers, tr)
+		}
+	}
+	add(mi.Announce)
+	for _, tier := range mi.AnnounceList {
+		for _, tr := range tier {
+			add(tr)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,123 @@
+package magnet
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ortymid/bencode/metainfo"
+)
+
+const (
+	hexV1    = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	base32V1 = "YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK"
+	hexV2    = "caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e"
+)
+
+func TestParse(t *testing.T) {
+	v1 := metainfo.HashV1{}
+	copy(v1[:], mustHex(hexV1))
+	v2 := metainfo.HashV2{}
+	copy(v2[:], mustHex(hexV2))
+
+	tests := []struct {
+		name  string
+		input string
+		want  *Magnet
+	}{
+		{"Hex", "magnet:?xt=urn:btih:" + hexV1, &Magnet{V1: &v1}},
+		{"Base32", "magnet:?xt=urn:btih:" + base32V1, &Magnet{V1: &v1}},
+		{"Upper hex", "magnet:?xt=urn:btih:" + strings.ToUpper(hexV1), &Magnet{V1: &v1}},
+		{"V2", "magnet:?xt=urn:btmh:1220" + hexV2, &Magnet{V2: &v2}},
+		{
+			"Hybrid with parameters",
+			"magnet:?xt=urn:btih:" + hexV1 + "&xt=urn:btmh:1220" + hexV2 +
+				"&dn=spam+%26+eggs&tr=udp%3A%2F%2Fb%3A1&tr.1=http%3A%2F%2Fa%2Fannounce&ws=http%3A%2F%2Fseed&x.pe=1.2.3.4%3A5&so=0,2,4-6",
+			&Magnet{
+				V1:          &v1,
+				V2:          &v2,
+				DisplayName: "spam & eggs",
+				Trackers:    []string{"udp://b:1", "http://a/announce"},
+				WebSeeds:    []string{"http://seed"},
+				Peers:       []string{"1.2.3.4:5"},
+				SelectOnly:  []Range{{0, 0}, {2, 2}, {4, 6}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.input)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", got, test.want)
+			}
+
+			// a built link parses back to the same magnet
+			again, err := Parse(got.String())
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(again, got) {
+				t.Errorf("\ngot: %+v \nwant: %+v", again, got)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []string{
+		"http://example.com",
+		"magnet:?dn=spam",
+		"magnet:?xt=urn:btih:123",
+		"magnet:?xt=urn:btih:" + strings.Repeat("z", 40),
+		"magnet:?xt=urn:btmh:1114" + hexV2,
+		"magnet:?xt=urn:btih:" + hexV1 + "&so=3-1",
+		"magnet:?xt=urn:btih:" + hexV1 + "&dn=%zz",
+	}
+	for _, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Error("no error for", input)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	v1 := metainfo.HashV1{}
+	copy(v1[:], mustHex(hexV1))
+	m := &Magnet{V1: &v1, DisplayName: "a b", Trackers: []string{"http://t/a?b=c"}, SelectOnly: []Range{{1, 1}, {3, 5}}}
+
+	want := "magnet:?xt=urn:btih:" + hexV1 + "&dn=a+b&tr=http%3A%2F%2Ft%2Fa%3Fb%3Dc&so=1,3-5"
+	if got := m.String(); got != want {
+		t.Errorf("\ngot: %s \nwant: %s", got, want)
+	}
+}
+
+func TestFromMetaInfo(t *testing.T) {
+	input := "d8:announce3:url13:announce-listll3:url4:url2ee4:infod6:lengthi5e4:name4:file12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "ee"
+	mi, err := metainfo.Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	got, err := FromMetaInfo(mi)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	h, _ := mi.InfoHashV1()
+	want := &Magnet{V1: &h, DisplayName: "file", Trackers: []string{"url", "url2"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, want)
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,291 @@
+package metainfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Version selects the kind of torrent a Builder produces.
+type Version int
+
+const (
+	// Version1 produces a v1 torrent (BEP 3).
+	Version1 Version = iota
+	// Version2 produces a v2 torrent (BEP 52).
+	Version2
+	// VersionHybrid produces a torrent with both v1 and v2 metadata.
+	VersionHybrid
+)
+
+const (
+	minPieceLength = BlockSize
+	maxPieceLength = 16 << 20
+	// targetPieces is the number of pieces automatic piece length aims at.
+	targetPieces = 1500
+)
+
+// Builder creates a MetaInfo from files on disk.
+type Builder struct {
+	// PieceLength is the length of a piece. If zero, it is chosen
+	// automatically from the total size of the content.
+	PieceLength int64
+	// Name is the name of the torrent. Defaults to the base name of the
+	// built path.
+	Name string
+	// Private sets the private flag (BEP 27).
+	Private bool
+	// Ignore holds filepath.Match patterns of files and directories to
+	// skip. A pattern is matched against both the base name and the
+	// slash-separated path relative to the built directory.
+	Ignore []string
+	// Padding inserts padding files (BEP 47) so that every file of a v1
+	// multi-file torrent starts at a piece boundary. It is implied by
+	// VersionHybrid.
+	Padding bool
+	// Version selects v1, v2 or hybrid metadata.
+	Version Version
+	// Workers is the number of goroutines hashing pieces. Defaults to the
+	// number of CPUs.
+	Workers int
+}
+
+// builderFile is a file found by the Builder.
+type builderFile struct {
+	path   []string // relative to the built directory
+	disk   string
+	length int64
+}
+
+// Build walks the file or directory at path, hashes its content and returns
+// the resulting metainfo. Only Info and PieceLayers are set; trackers and
+// other fields are left to the caller.
+func (b *Builder) Build(path string) (*MetaInfo, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files, err := b.walk(path, st)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.length
+	}
+
+	info := Info{Name: b.Name, PieceLength: b.PieceLength}
+	if info.Name == "" {
+		info.Name = filepath.Base(filepath.Clean(path))
+	}
+	if !st.IsDir() {
+		// a single file is named by the torrent name
+		files[0].path = []string{info.Name}
+	}
+	if info.PieceLength == 0 {
+		info.PieceLength = AutoPieceLength(total)
+	}
+	if b.Private {
+		info.Private = 1
+	}
+
+	mi := &MetaInfo{Info: info}
+	if b.Version == Version1 || b.Version == VersionHybrid {
+		if err := b.buildV1(&mi.Info, files, !st.IsDir()); err != nil {
+			return nil, err
+		}
+	}
+	if b.Version == Version2 || b.Version == VersionHybrid {
+		if err := b.buildV2(mi, files); err != nil {
+			return nil, err
+		}
+	}
+	if err := mi.Validate(); err != nil {
+		return nil, err
+	}
+	return mi, nil
+}
+
+// AutoPieceLength returns a power of two piece length which splits content
+// of the given size into about 1500 pieces, within 16 KiB and 16 MiB.
+func AutoPieceLength(size int64) int64 {
+	pl := int64(minPieceLength)
+	for pl < maxPieceLength && size/pl > targetPieces {
+		pl *= 2
+	}
+	return pl
+}
+
+// walk returns the regular files under path in the canonical order.
+func (b *Builder) walk(path string, st os.FileInfo) ([]builderFile, error) {
+	if !st.IsDir() {
+		return []builderFile{{path: []string{st.Name()}, disk: path, length: st.Size()}}, nil
+	}
+
+	var files []builderFile
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == path {
+			return nil
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if b.ignored(d.Name(), rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, builderFile{path: strings.Split(rel, "/"), disk: p, length: fi.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("metainfo: no files in %s", path)
+	}
+	return files, nil
+}
+
+func (b *Builder) ignored(name, rel string) bool {
+	for _, pattern := range b.Ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildV1 sets the file list and the SHA-1 piece hashes of info.
+func (b *Builder) buildV1(info *Info, files []builderFile, single bool) error {
+	pl := info.PieceLength
+	s := &storage{}
+	if single {
+		length := files[0].length
+		info.Length = &length
+		s.add(files[0].disk, length)
+	} else {
+		padding := b.Padding || b.Version == VersionHybrid
+		for i, f := range files {
+			info.Files = append(info.Files, File{Length: f.length, Path: f.path})
+			s.add(f.disk, f.length)
+			if rest := f.length % pl; padding && rest != 0 && i < len(files)-1 {
+				pad := pl - rest
+				info.Files = append(info.Files, File{
+					Attr:   "p",
+					Length: pad,
+					Path:   []string{".pad", strconv.FormatInt(pad, 10)},
+				})
+				s.add("", pad)
+			}
+		}
+	}
+
+	n := int((s.size + pl - 1) / pl)
+	hashes := make([]HashV1, n)
+	err := parallel(b.Workers, n, func(i int) (err error) {
+		hashes[i], err = hashPieceV1(s, pl, i)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	pieces := make([]byte, 0, n*HashSize)
+	for _, h := range hashes {
+		pieces = append(pieces, h[:]...)
+	}
+	info.Pieces = string(pieces)
+	return nil
+}
+
+// buildV2 sets the file tree of the info dict and the piece layers of mi.
+func (b *Builder) buildV2(mi *MetaInfo, files []builderFile) error {
+	pl := mi.Info.PieceLength
+	if pl < BlockSize || pl&(pl-1) != 0 {
+		return errors.New("metainfo: v2 piece length must be a power of two of at least 16 KiB")
+	}
+
+	// a job is a piece of a file
+	type job struct {
+		file  int
+		piece int
+	}
+	var (
+		jobs     []job
+		storages = make([]*storage, len(files))
+		blocks   = make([][][]HashV2, len(files)) // file, piece, block
+	)
+	for i, f := range files {
+		s := &storage{}
+		s.add(f.disk, f.length)
+		storages[i] = s
+		n := int((f.length + pl - 1) / pl)
+		blocks[i] = make([][]HashV2, n)
+		for p := 0; p < n; p++ {
+			jobs = append(jobs, job{i, p})
+		}
+	}
+	err := parallel(b.Workers, len(jobs), func(i int) (err error) {
+		j := jobs[i]
+		blocks[j.file][j.piece], err = hashPieceBlocks(storages[j.file], pl, j.piece)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	v2 := make([]FileV2, len(files))
+	for i, f := range files {
+		v2[i] = FileV2{Path: f.path, Length: f.length}
+		switch pieces := blocks[i]; {
+		case len(pieces) == 0:
+			// empty files have no pieces root
+		case len(pieces) == 1:
+			root := BlockRoot(pieces[0])
+			v2[i].PiecesRoot = string(root[:])
+		default:
+			layer := make([]HashV2, len(pieces))
+			for p, pb := range pieces {
+				layer[p] = pieceRootV2(pb, pl)
+			}
+			root := PieceLayerRoot(layer, pl)
+			v2[i].PiecesRoot = string(root[:])
+			if mi.PieceLayers == nil {
+				mi.PieceLayers = make(map[string]string)
+			}
+			mi.PieceLayers[v2[i].PiecesRoot] = joinHashV2(layer)
+		}
+	}
+	mi.Info.MetaVersion = 2
+	mi.Info.FileTree = NewFileTree(v2)
+	return nil
+}
+
+func joinHashV2(hashes []HashV2) string {
+	b := make([]byte, 0, len(hashes)*len(HashV2{}))
+	for _, h := range hashes {
+		b = append(b, h[:]...)
+	}
+	return string(b)
+}
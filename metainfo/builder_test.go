@@ -0,0 +1,119 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string][]byte) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, "dir", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "dir")
+}
+
+func TestBuilderHybrid(t *testing.T) {
+	dir := writeFiles(t, map[string][]byte{
+		"a":          bytes.Repeat([]byte("a"), 40000),
+		"sub/b":      bytes.Repeat([]byte("b"), 100),
+		"sub/ignore": []byte("ignored"),
+		".git/HEAD":  []byte("ignored"),
+	})
+
+	b := &Builder{PieceLength: BlockSize, Version: VersionHybrid, Ignore: []string{".git", "sub/ignore"}}
+	got, err := b.Build(dir)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := hybrid()
+	if !reflect.DeepEqual(got.Info, want.Info) {
+		t.Errorf("\ngot: %+v \nwant: %+v", got.Info, want.Info)
+	}
+	if !reflect.DeepEqual(got.PieceLayers, want.PieceLayers) {
+		t.Error("piece layers differ")
+	}
+}
+
+func TestBuilderSingleFile(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 5000)
+	dir := writeFiles(t, map[string][]byte{"file": data})
+
+	for _, version := range []Version{Version1, Version2, VersionHybrid} {
+		b := &Builder{Name: "renamed", Version: version, Workers: 3}
+		mi, err := b.Build(filepath.Join(dir, "file"))
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if mi.Info.Name != "renamed" || mi.Info.PieceLength != BlockSize {
+			t.Errorf("got name %s and piece length %d", mi.Info.Name, mi.Info.PieceLength)
+		}
+		if mi.Info.TotalLength() != int64(len(data)) {
+			t.Error("got:", mi.Info.TotalLength(), "want:", len(data))
+		}
+		if version == Version2 {
+			continue
+		}
+		for i := 0; i < mi.Info.NumPieces(); i++ {
+			end := (i + 1) * BlockSize
+			if end > len(data) {
+				end = len(data)
+			}
+			if h := sha1.Sum(data[i*BlockSize : end]); !bytes.Equal(mi.Info.PieceHash(i), h[:]) {
+				t.Error("wrong hash of piece", i)
+			}
+		}
+	}
+}
+
+func TestBuilderPadding(t *testing.T) {
+	dir := writeFiles(t, map[string][]byte{"a": []byte("spam"), "b": []byte("eggs"), "c": nil})
+
+	mi, err := (&Builder{Padding: true}).Build(dir)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := []File{
+		{Length: 4, Path: []string{"a"}},
+		{Attr: "p", Length: BlockSize - 4, Path: []string{".pad", "16380"}},
+		{Length: 4, Path: []string{"b"}},
+		{Attr: "p", Length: BlockSize - 4, Path: []string{".pad", "16380"}},
+		{Length: 0, Path: []string{"c"}},
+	}
+	if !reflect.DeepEqual(mi.Info.Files, want) {
+		t.Errorf("\ngot: %+v \nwant: %+v", mi.Info.Files, want)
+	}
+}
+
+func TestBuilderEmpty(t *testing.T) {
+	if _, err := (&Builder{}).Build(t.TempDir()); err == nil {
+		t.Error("no error for an empty directory")
+	}
+}
+
+func TestAutoPieceLength(t *testing.T) {
+	tests := []struct {
+		size int64
+		want int64
+	}{
+		{0, 16 << 10},
+		{1 << 20, 16 << 10},
+		{1 << 30, 1 << 20},
+		{1 << 40, 16 << 20},
+	}
+	for _, test := range tests {
+		if got := AutoPieceLength(test.size); got != test.want {
+			t.Error("size:", test.size, "got:", got, "want:", test.want)
+		}
+	}
+}
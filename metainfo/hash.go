@@ -0,0 +1,104 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/ortymid/bencode"
+)
+
+// HashV1 is a SHA-1 info-hash of a v1 torrent.
+type HashV1 [sha1.Size]byte
+
+// String returns the hash in hex.
+func (h HashV1) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// HashV2 is a SHA-256 info-hash of a v2 torrent.
+type HashV2 [sha256.Size]byte
+
+// String returns the hash in hex.
+func (h HashV2) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// Truncate returns the first 20 bytes of the hash, which is the form used
+// by the tracker and DHT protocols.
+func (h HashV2) Truncate() HashV1 {
+	var t HashV1
+	copy(t[:], h[:])
+	return t
+}
+
+// rawInfo holds the info dict bytes as they were loaded.
+type rawInfo struct {
+	// original is the exact bytes of the info dict.
+	original []byte
+	// canonical is the canonical encoding of the decoded Info, used to
+	// detect changes to Info made after loading.
+	canonical []byte
+}
+
+// InfoBytes returns the bytes the info-hash is computed over. For a loaded
+// metainfo these are the exact bytes of the info dict as they were read,
+// unless Info has been changed since. Otherwise it is the canonical encoding
+// of Info.
+func (mi *MetaInfo) InfoBytes() ([]byte, error) {
+	canonical, err := mi.Info.Bencode()
+	if err != nil {
+		return nil, err
+	}
+	if mi.raw != nil && bytes.Equal(canonical, mi.raw.canonical) {
+		return mi.raw.original, nil
+	}
+	return canonical, nil
+}
+
+// InfoHashV1 returns the SHA-1 hash of the info dict as returned by
+// InfoBytes.
+func (mi *MetaInfo) InfoHashV1() (HashV1, error) {
+	b, err := mi.InfoBytes()
+	if err != nil {
+		return HashV1{}, err
+	}
+	return sha1.Sum(b), nil
+}
+
+// InfoHashV2 returns the SHA-256 hash of the info dict as returned by
+// InfoBytes.
+func (mi *MetaInfo) InfoHashV2() (HashV2, error) {
+	b, err := mi.InfoBytes()
+	if err != nil {
+		return HashV2{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// Bencode returns the canonical encoding of the info dict.
+func (info *Info) Bencode() ([]byte, error) {
+	return bencode.Marshal(info)
+}
+
+// HashV1 returns the SHA-1 hash of the canonical encoding of the info dict.
+// Use it for torrents generated in code; for loaded torrents use
+// MetaInfo.InfoHashV1, which hashes the original bytes.
+func (info *Info) HashV1() (HashV1, error) {
+	b, err := info.Bencode()
+	if err != nil {
+		return HashV1{}, err
+	}
+	return sha1.Sum(b), nil
+}
+
+// HashV2 returns the SHA-256 hash of the canonical encoding of the info
+// dict.
+func (info *Info) HashV2() (HashV2, error) {
+	b, err := info.Bencode()
+	if err != nil {
+		return HashV2{}, err
+	}
+	return sha256.Sum256(b), nil
+}
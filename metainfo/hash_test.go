@@ -0,0 +1,135 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestInfoHash(t *testing.T) {
+	// the info dict is not canonical: the keys are not sorted
+	info := "d4:name4:file6:lengthi5e12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "e"
+	input := "d8:announce3:url4:info" + info + "e"
+
+	mi, err := Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	t.Run("Original bytes", func(t *testing.T) {
+		got, err := mi.InfoBytes()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if string(got) != info {
+			t.Errorf("\ngot: %s \nwant: %s", got, info)
+		}
+
+		v1, err := mi.InfoHashV1()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if want := HashV1(sha1.Sum([]byte(info))); v1 != want {
+			t.Error("got:", v1, "want:", want)
+		}
+
+		v2, err := mi.InfoHashV2()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if want := HashV2(sha256.Sum256([]byte(info))); v2 != want {
+			t.Error("got:", v2, "want:", want)
+		}
+	})
+
+	t.Run("Write keeps info", func(t *testing.T) {
+		mi := *mi
+		mi.Comment = "spam"
+
+		var buf bytes.Buffer
+		if err := mi.Write(&buf); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		want := "d8:announce3:url7:comment4:spam4:info" + info + "e"
+		if buf.String() != want {
+			t.Errorf("\ngot: %s \nwant: %s", buf.String(), want)
+		}
+	})
+
+	t.Run("Changed info", func(t *testing.T) {
+		mi := *mi
+		mi.Info.Private = 1
+
+		got, err := mi.InfoHashV1()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		want, err := mi.Info.HashV1()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if got != want {
+			t.Error("got:", got, "want:", want)
+		}
+	})
+}
+
+func TestHashV2Truncate(t *testing.T) {
+	var h HashV2
+	for i := range h {
+		h[i] = byte(i)
+	}
+	got := h.Truncate()
+	if !bytes.Equal(got[:], h[:20]) {
+		t.Error("got:", got, "want:", h)
+	}
+}
+
+func TestExtraKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		info  string
+		want  string
+		check func(mi *MetaInfo) bool
+	}{
+		{
+			"Info",
+			"d6:lengthi5e4:name4:file12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "6:source3:abce",
+			"d6:lengthi5e4:name4:file12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "7:privatei1e6:source3:abce",
+			func(mi *MetaInfo) bool { return string(mi.Info.Extra["source"]) == "3:abc" },
+		},
+		{
+			"File",
+			"d5:filesld6:lengthi5e6:md5sum2:ab4:pathl1:aeee4:name3:dir12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "e",
+			"d5:filesld6:lengthi5e6:md5sum2:ab4:pathl1:aeee4:name3:dir12:piece lengthi8e6:pieces20:" + strings.Repeat("x", 20) + "7:privatei1ee",
+			func(mi *MetaInfo) bool { return string(mi.Info.Files[0].Extra["md5sum"]) == "2:ab" },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := "d8:announce3:url4:info" + test.info + "13:publisher-url3:urle"
+			mi, err := Load(strings.NewReader(input))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !test.check(mi) {
+				t.Errorf("extra keys not loaded: %+v", mi.Info)
+			}
+
+			// changing the info dict reencodes it with the extra keys
+			mi.Info.Private = 1
+			mi.Comment = "spam"
+			var buf bytes.Buffer
+			if err := mi.Write(&buf); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			want := "d8:announce3:url7:comment4:spam4:info" + test.want + "13:publisher-url3:urle"
+			if buf.String() != want {
+				t.Errorf("\ngot: %s \nwant: %s", buf.String(), want)
+			}
+		})
+	}
+}
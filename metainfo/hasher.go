@@ -0,0 +1,98 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"runtime"
+	"sync"
+)
+
+// parallel calls fn for every index in [0, n) from the given number of
+// worker goroutines and returns the first error. After an error the
+// remaining indices are skipped.
+func parallel(workers, n int, fn func(i int) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		stop  = make(chan struct{})
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						first = err
+						close(stop)
+					})
+				}
+			}
+		}()
+	}
+
+Loop:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break Loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return first
+}
+
+// pieceSize returns the size of the i-th piece of the content of the given
+// size.
+func pieceSize(size, pieceLength int64, i int) int64 {
+	if rest := size - int64(i)*pieceLength; rest < pieceLength {
+		return rest
+	}
+	return pieceLength
+}
+
+// hashPieceV1 returns the SHA-1 hash of the i-th piece of s.
+func hashPieceV1(s *storage, pieceLength int64, i int) (HashV1, error) {
+	buf := make([]byte, pieceSize(s.size, pieceLength, i))
+	if _, err := s.ReadAt(buf, int64(i)*pieceLength); err != nil {
+		return HashV1{}, err
+	}
+	return sha1.Sum(buf), nil
+}
+
+// hashPieceBlocks returns the SHA-256 hashes of the 16 KiB blocks of the
+// i-th piece of s.
+func hashPieceBlocks(s *storage, pieceLength int64, i int) ([]HashV2, error) {
+	buf := make([]byte, pieceSize(s.size, pieceLength, i))
+	if _, err := s.ReadAt(buf, int64(i)*pieceLength); err != nil {
+		return nil, err
+	}
+	var blocks []HashV2
+	for off := 0; off < len(buf); off += BlockSize {
+		end := off + BlockSize
+		if end > len(buf) {
+			end = len(buf)
+		}
+		blocks = append(blocks, sha256.Sum256(buf[off:end]))
+	}
+	return blocks, nil
+}
+
+// pieceRootV2 returns the root of the subtree of a piece from the hashes of
+// its blocks, padded with zero leaves up to the piece size.
+func pieceRootV2(blocks []HashV2, pieceLength int64) HashV2 {
+	perPiece := int(pieceLength / BlockSize)
+	padded := append(blocks[:len(blocks):len(blocks)], make([]HashV2, perPiece-len(blocks))...)
+	return merkleRoot(padded, HashV2{})
+}
@@ -0,0 +1,96 @@
+package metainfo
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// BlockSize is the size of the leaf blocks of a v2 merkle tree.
+const BlockSize = 16 << 10
+
+// hashPair returns the hash of the concatenation of two child hashes.
+func hashPair(a, b HashV2) HashV2 {
+	var buf [2 * sha256.Size]byte
+	copy(buf[:], a[:])
+	copy(buf[sha256.Size:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+// zeroRoot returns the root of a merkle tree of n zero leaves, n being
+// a power of two.
+func zeroRoot(n int64) HashV2 {
+	var h HashV2
+	for ; n > 1; n /= 2 {
+		h = hashPair(h, h)
+	}
+	return h
+}
+
+// merkleRoot returns the root of a tree built over hashes, padded up to
+// a power of two with pad.
+func merkleRoot(hashes []HashV2, pad HashV2) HashV2 {
+	if len(hashes) == 0 {
+		return HashV2{}
+	}
+	layer := append([]HashV2(nil), hashes...)
+	for len(layer) > 1 {
+		if len(layer)%2 != 0 {
+			layer = append(layer, pad)
+		}
+		next := layer[:0:0]
+		for i := 0; i < len(layer); i += 2 {
+			next = append(next, hashPair(layer[i], layer[i+1]))
+		}
+		layer = next
+		pad = hashPair(pad, pad)
+	}
+	return layer[0]
+}
+
+// BlockRoot returns the merkle root of a file from the SHA-256 hashes of its
+// 16 KiB blocks. It is the "pieces root" of a file which fits into a single
+// piece.
+func BlockRoot(blocks []HashV2) HashV2 {
+	return merkleRoot(blocks, HashV2{})
+}
+
+// PieceLayer returns the layer of a file's merkle tree which corresponds
+// to pieces of pieceLength bytes, computed from the hashes of its blocks.
+func PieceLayer(blocks []HashV2, pieceLength int64) []HashV2 {
+	perPiece := int(pieceLength / BlockSize)
+	var layer []HashV2
+	for i := 0; i < len(blocks); i += perPiece {
+		end := i + perPiece
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		layer = append(layer, pieceRootV2(blocks[i:end], pieceLength))
+	}
+	return layer
+}
+
+// PieceLayerRoot returns the merkle root of a file from its piece layer.
+func PieceLayerRoot(layer []HashV2, pieceLength int64) HashV2 {
+	return merkleRoot(layer, zeroRoot(pieceLength/BlockSize))
+}
+
+// splitHashes splits concatenated hashes, as stored in piece layers.
+func splitHashes(s string) ([]HashV2, error) {
+	if len(s)%sha256.Size != 0 {
+		return nil, fmt.Errorf("length %d is not a multiple of %d", len(s), sha256.Size)
+	}
+	hashes := make([]HashV2, len(s)/sha256.Size)
+	for i := range hashes {
+		copy(hashes[i][:], s[i*sha256.Size:])
+	}
+	return hashes, nil
+}
+
+// FileRoot returns the "pieces root" of a file from the SHA-256 hashes of
+// its 16 KiB blocks.
+func FileRoot(blocks []HashV2, pieceLength int64) HashV2 {
+	if int64(len(blocks))*BlockSize <= pieceLength {
+		return BlockRoot(blocks)
+	}
+	return PieceLayerRoot(PieceLayer(blocks, pieceLength), pieceLength)
+}
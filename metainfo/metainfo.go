@@ -0,0 +1,230 @@
+// Package metainfo provides the types of BitTorrent metainfo (.torrent)
+// files as described in BEP 3, including v2 and hybrid torrents (BEP 52).
+package metainfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ortymid/bencode"
+)
+
+// HashSize is the length of a SHA-1 piece hash.
+const HashSize = 20
+
+// MetaInfo is the content of a .torrent file.
+type MetaInfo struct {
+	Announce     string     `bencode:"announce,omitempty"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	Comment      string     `bencode:"comment,omitempty"`
+	CreatedBy    string     `bencode:"created by,omitempty"`
+	CreationDate int64      `bencode:"creation date,omitempty"`
+	Encoding     string     `bencode:"encoding,omitempty"`
+	Info         Info       `bencode:"info"`
+	// PieceLayers maps the pieces root of each v2 file larger than a piece
+	// to the concatenated hashes of its pieces.
+	PieceLayers map[string]string `bencode:"piece layers,omitempty"`
+	// Extra holds the keys of other extensions, such as "publisher-url",
+	// which are written back as they were read.
+	Extra map[string]bencode.RawMessage `bencode:",rest"`
+
+	raw *rawInfo `bencode:"-"`
+}
+
+// Info is the info dictionary of a torrent. A v1 torrent has exactly one of
+// Length and Files set: Length for a single-file torrent, Files for
+// a multi-file one. A v2 torrent has MetaVersion 2 and FileTree set.
+// A hybrid torrent has both.
+type Info struct {
+	PieceLength int64    `bencode:"piece length"`
+	Pieces      string   `bencode:"pieces,omitempty"`
+	Private     int64    `bencode:"private,omitempty"`
+	Name        string   `bencode:"name"`
+	Length      *int64   `bencode:"length,omitempty"`
+	Files       []File   `bencode:"files,omitempty"`
+	MetaVersion int64    `bencode:"meta version,omitempty"`
+	FileTree    FileTree `bencode:"file tree,omitempty"`
+	// Extra holds the keys of other extensions, such as "source", which
+	// are written back as they were read and so keep the info-hash.
+	Extra map[string]bencode.RawMessage `bencode:",rest"`
+}
+
+// File is a file of a multi-file v1 torrent.
+type File struct {
+	Attr   string   `bencode:"attr,omitempty"`
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+	// Extra holds the keys of other extensions, such as "md5sum" or the
+	// "sha1" and "symlink path" of BEP 47.
+	Extra map[string]bencode.RawMessage `bencode:",rest"`
+}
+
+// IsPadding reports whether f is a padding file (BEP 47).
+func (f *File) IsPadding() bool {
+	return strings.Contains(f.Attr, "p")
+}
+
+// Load reads and validates a metainfo. The exact bytes of the info dict
+// are kept, so that the info-hash and Write do not depend on whether the
+// info dict was in the canonical form.
+func Load(r io.Reader) (*MetaInfo, error) {
+	p := bencode.NewParser(r)
+	p.CaptureRaw(true)
+	v, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	mi := &MetaInfo{}
+	if err := bencode.UnmarshalValue(v, mi); err != nil {
+		return nil, err
+	}
+	if err := mi.Validate(); err != nil {
+		return nil, err
+	}
+
+	// validation guarantees that the info dict is present
+	info := v.(*bencode.Dict).Get("info").(*bencode.Dict)
+	canonical, err := mi.Info.Bencode()
+	if err != nil {
+		return nil, err
+	}
+	mi.raw = &rawInfo{original: info.Raw(), canonical: canonical}
+	return mi, nil
+}
+
+// LoadFile reads and validates a metainfo from the named file.
+func LoadFile(name string) (*MetaInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Write validates mi and writes it to w in the canonical form. The info
+// dict is written as returned by InfoBytes, so that a loaded and unchanged
+// info dict keeps its info-hash.
+func (mi *MetaInfo) Write(w io.Writer) error {
+	if err := mi.Validate(); err != nil {
+		return err
+	}
+	info, err := mi.InfoBytes()
+	if err != nil {
+		return err
+	}
+	data, err := bencode.Marshal(mi)
+	if err != nil {
+		return err
+	}
+
+	// replace the info dict in the canonical encoding with info
+	var m map[string][]byte
+	if err := bencode.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	m["info"] = info
+	return bencode.NewEncoder(w).Encode(m)
+}
+
+// Validate checks the invariants of the metainfo.
+func (mi *MetaInfo) Validate() error {
+	if err := mi.Info.Validate(); err != nil {
+		return err
+	}
+	if mi.Info.HasV2() {
+		return mi.validatePieceLayers()
+	}
+	return nil
+}
+
+// Validate checks the invariants of the info dictionary.
+func (info *Info) Validate() error {
+	if info.Name == "" {
+		return errors.New("metainfo: name is empty")
+	}
+	if info.PieceLength <= 0 {
+		return fmt.Errorf("metainfo: invalid piece length %d", info.PieceLength)
+	}
+	if !info.HasV1() && !info.HasV2() {
+		return errors.New("metainfo: neither v1 nor v2 torrent")
+	}
+	if info.HasV1() {
+		if err := info.validateV1(); err != nil {
+			return err
+		}
+	}
+	if info.HasV2() {
+		if err := info.validateV2(); err != nil {
+			return err
+		}
+	}
+	if info.IsHybrid() {
+		return info.validateHybrid()
+	}
+	return nil
+}
+
+func (info *Info) validateV1() error {
+	if len(info.Pieces)%HashSize != 0 {
+		return fmt.Errorf("metainfo: pieces length %d is not a multiple of %d", len(info.Pieces), HashSize)
+	}
+
+	switch {
+	case info.Length != nil && info.Files != nil:
+		return errors.New("metainfo: both length and files are present")
+	case info.Length == nil && info.Files == nil:
+		return errors.New("metainfo: neither length nor files is present")
+	case info.Length != nil && *info.Length < 0:
+		return fmt.Errorf("metainfo: invalid length %d", *info.Length)
+	}
+	for i, f := range info.Files {
+		if f.Length < 0 {
+			return fmt.Errorf("metainfo: file %d: invalid length %d", i, f.Length)
+		}
+		if len(f.Path) == 0 {
+			return fmt.Errorf("metainfo: file %d: path is empty", i)
+		}
+	}
+
+	if want := info.NumPieces(); len(info.Pieces)/HashSize != want {
+		return fmt.Errorf("metainfo: got %d piece hashes, want %d", len(info.Pieces)/HashSize, want)
+	}
+	return nil
+}
+
+// TotalLength returns the sum of the lengths of all files. For v1 and
+// hybrid torrents it includes padding files.
+func (info *Info) TotalLength() int64 {
+	if info.Length != nil {
+		return *info.Length
+	}
+	var n int64
+	if !info.HasV1() {
+		files, _ := info.FilesV2()
+		for _, f := range files {
+			n += f.Length
+		}
+		return n
+	}
+	for _, f := range info.Files {
+		n += f.Length
+	}
+	return n
+}
+
+// NumPieces returns the number of v1 pieces the content is split into.
+func (info *Info) NumPieces() int {
+	if info.PieceLength <= 0 {
+		return 0
+	}
+	return int((info.TotalLength() + info.PieceLength - 1) / info.PieceLength)
+}
+
+// PieceHash returns the SHA-1 hash of the i-th piece.
+func (info *Info) PieceHash(i int) []byte {
+	return []byte(info.Pieces[i*HashSize : (i+1)*HashSize])
+}
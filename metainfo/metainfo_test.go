@@ -0,0 +1,104 @@
+package metainfo
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func length(n int64) *int64 { return &n }
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *MetaInfo
+	}{
+		{
+			"Single file",
+			"d8:announce3:url13:announce-listll3:url4:url2el4:url3ee7:comment4:spam10:created by4:eggs13:creation datei42e4:infod6:lengthi5e4:name4:file12:piece lengthi4e6:pieces40:" + strings.Repeat("x", 40) + "7:privatei1eee",
+			&MetaInfo{
+				Announce:     "url",
+				AnnounceList: [][]string{{"url", "url2"}, {"url3"}},
+				Comment:      "spam",
+				CreatedBy:    "eggs",
+				CreationDate: 42,
+				Info: Info{
+					PieceLength: 4,
+					Pieces:      strings.Repeat("x", 40),
+					Private:     1,
+					Name:        "file",
+					Length:      length(5),
+				},
+			},
+		},
+		{
+			"Multiple files",
+			"d4:infod5:filesld6:lengthi3e4:pathl1:a1:beed6:lengthi0e4:pathl1:ceee4:name3:dir12:piece lengthi4e6:pieces20:" + strings.Repeat("x", 20) + "ee",
+			&MetaInfo{
+				Info: Info{
+					PieceLength: 4,
+					Pieces:      strings.Repeat("x", 20),
+					Name:        "dir",
+					Files:       []File{{Length: 3, Path: []string{"a", "b"}}, {Length: 0, Path: []string{"c"}}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Load(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			loaded := *got
+			loaded.raw = nil
+			if !reflect.DeepEqual(&loaded, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", &loaded, test.want)
+			}
+
+			var buf bytes.Buffer
+			if err := got.Write(&buf); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if buf.String() != test.input {
+				t.Errorf("\ngot: %s \nwant: %s", buf.String(), test.input)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Info {
+		return Info{PieceLength: 4, Pieces: strings.Repeat("x", 40), Name: "file", Length: length(5)}
+	}
+	tests := []struct {
+		name   string
+		modify func(*Info)
+	}{
+		{"No name", func(info *Info) { info.Name = "" }},
+		{"Zero piece length", func(info *Info) { info.PieceLength = 0 }},
+		{"Pieces length", func(info *Info) { info.Pieces = info.Pieces[:39] }},
+		{"Pieces count", func(info *Info) { info.Pieces = info.Pieces[:20] }},
+		{"Length and files", func(info *Info) { info.Files = []File{{Length: 5, Path: []string{"a"}}} }},
+		{"No length nor files", func(info *Info) { info.Length = nil }},
+		{"Negative length", func(info *Info) { info.Length = length(-1) }},
+		{"Empty path", func(info *Info) { info.Length = nil; info.Files = []File{{Length: 5}} }},
+	}
+
+	info := valid()
+	if err := info.Validate(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := valid()
+			test.modify(&info)
+			if err := info.Validate(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
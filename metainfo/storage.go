@@ -0,0 +1,89 @@
+package metainfo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// storageFile is a file of the content laid out as a single stream.
+type storageFile struct {
+	// path is the location of the file on disk, empty for padding files.
+	path   string
+	offset int64
+	length int64
+}
+
+// storage reads ranges of the content of a torrent, which is the
+// concatenation of its files, as if it were a single stream.
+type storage struct {
+	files []storageFile
+	size  int64
+}
+
+func (s *storage) add(path string, length int64) {
+	s.files = append(s.files, storageFile{path: path, offset: s.size, length: length})
+	s.size += length
+}
+
+// ReadAt reads len(p) bytes at offset off. Padding files read as zeros.
+// It is safe to call ReadAt concurrently.
+func (s *storage) ReadAt(p []byte, off int64) (int, error) {
+	// find the first file which ends after off
+	i := sort.Search(len(s.files), func(i int) bool {
+		return s.files[i].offset+s.files[i].length > off
+	})
+
+	n := 0
+	for ; n < len(p) && i < len(s.files); i++ {
+		f := s.files[i]
+		start := off + int64(n) - f.offset
+		chunk := p[n:]
+		if rest := f.length - start; int64(len(chunk)) > rest {
+			chunk = chunk[:rest]
+		}
+		if err := f.readAt(chunk, start); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f storageFile) readAt(p []byte, off int64) error {
+	if f.path == "" {
+		for i := range p {
+			p[i] = 0
+		}
+		return nil
+	}
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	if _, err := fd.ReadAt(p, off); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("metainfo: %s is shorter than %d bytes", f.path, f.length)
+		}
+		return err
+	}
+	return nil
+}
+
+// section returns a storage of a single file.
+func (s *storage) section(i int) *storage {
+	f := s.files[i]
+	f.offset = 0
+	return &storage{files: []storageFile{f}, size: f.length}
+}
+
+// filePath joins the components of a torrent path to the base directory.
+func filePath(base string, path []string) string {
+	return filepath.Join(append([]string{base}, path...)...)
+}
@@ -0,0 +1,205 @@
+package metainfo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileTree is the "file tree" of a v2 torrent (BEP 52). Each key is a path
+// component mapped to a nested tree; a file is a tree with the single empty
+// key mapped to a dict with "length" and "pieces root". Nested trees are
+// stored as map[string]interface{} as they come out of the decoder; use
+// Info.FilesV2 and NewFileTree to work with them.
+type FileTree map[string]interface{}
+
+// FileV2 is a file of a v2 torrent.
+type FileV2 struct {
+	Path   []string
+	Length int64
+	// PiecesRoot is the merkle root of the file, empty for empty files.
+	PiecesRoot string
+}
+
+// NewFileTree builds a file tree from files.
+func NewFileTree(files []FileV2) FileTree {
+	tree := FileTree{}
+	for _, f := range files {
+		node := map[string]interface{}(tree)
+		for _, name := range f.Path {
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[name] = child
+			}
+			node = child
+		}
+		entry := map[string]interface{}{"length": f.Length}
+		if f.PiecesRoot != "" {
+			entry["pieces root"] = f.PiecesRoot
+		}
+		node[""] = entry
+	}
+	return tree
+}
+
+// FilesV2 returns the files of the file tree in the canonical order.
+func (info *Info) FilesV2() ([]FileV2, error) {
+	var files []FileV2
+	if err := walkFileTree(info.FileTree, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func walkFileTree(node map[string]interface{}, path []string, files *[]FileV2) error {
+	if len(node) == 0 {
+		return fmt.Errorf("metainfo: file tree %q: empty directory", strings.Join(path, "/"))
+	}
+	if entry, ok := node[""]; ok {
+		if len(node) != 1 || len(path) == 0 {
+			return fmt.Errorf("metainfo: file tree %q: file entry mixed with directory", strings.Join(path, "/"))
+		}
+		f, err := fileTreeEntry(entry)
+		if err != nil {
+			return fmt.Errorf("metainfo: file tree %q: %w", strings.Join(path, "/"), err)
+		}
+		f.Path = append([]string(nil), path...)
+		*files = append(*files, f)
+		return nil
+	}
+
+	names := make([]string, 0, len(node))
+	for name := range node {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child, ok := node[name].(map[string]interface{})
+		if !ok {
+			if tree, isTree := node[name].(FileTree); isTree {
+				child, ok = tree, true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("metainfo: file tree %q: %q is not a dict", strings.Join(path, "/"), name)
+		}
+		if err := walkFileTree(child, append(path, name), files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileTreeEntry(v interface{}) (FileV2, error) {
+	entry, ok := v.(map[string]interface{})
+	if !ok {
+		return FileV2{}, errors.New("file entry is not a dict")
+	}
+	length, ok := entry["length"].(int64)
+	if !ok || length < 0 {
+		return FileV2{}, errors.New("invalid length")
+	}
+	f := FileV2{Length: length}
+	if root, ok := entry["pieces root"]; ok {
+		f.PiecesRoot, ok = root.(string)
+		if !ok || len(f.PiecesRoot) != sha256.Size {
+			return FileV2{}, errors.New("invalid pieces root")
+		}
+	}
+	if length > 0 && f.PiecesRoot == "" {
+		return FileV2{}, errors.New("pieces root is missing")
+	}
+	return f, nil
+}
+
+// HasV1 reports whether the info dict describes a v1 torrent.
+func (info *Info) HasV1() bool {
+	return info.Pieces != "" || info.Length != nil || info.Files != nil
+}
+
+// HasV2 reports whether the info dict describes a v2 torrent.
+func (info *Info) HasV2() bool {
+	return info.MetaVersion == 2 || info.FileTree != nil
+}
+
+// IsHybrid reports whether the info dict describes both a v1 and a v2
+// torrent.
+func (info *Info) IsHybrid() bool {
+	return info.HasV1() && info.HasV2()
+}
+
+func (info *Info) validateV2() error {
+	if info.MetaVersion != 2 {
+		return fmt.Errorf("metainfo: unsupported meta version %d", info.MetaVersion)
+	}
+	if info.FileTree == nil {
+		return errors.New("metainfo: file tree is missing")
+	}
+	if info.PieceLength < BlockSize || info.PieceLength&(info.PieceLength-1) != 0 {
+		return fmt.Errorf("metainfo: piece length %d is not a power of two of at least %d", info.PieceLength, BlockSize)
+	}
+	_, err := info.FilesV2()
+	return err
+}
+
+// validateHybrid checks that the v1 and v2 views describe the same files.
+func (info *Info) validateHybrid() error {
+	v2, err := info.FilesV2()
+	if err != nil {
+		return err
+	}
+
+	var v1 []File
+	if info.Length != nil {
+		v1 = []File{{Length: *info.Length, Path: []string{info.Name}}}
+	}
+	for _, f := range info.Files {
+		if !f.IsPadding() {
+			v1 = append(v1, f)
+		}
+	}
+
+	if len(v1) != len(v2) {
+		return fmt.Errorf("metainfo: hybrid torrent has %d v1 files and %d v2 files", len(v1), len(v2))
+	}
+	for i := range v1 {
+		if strings.Join(v1[i].Path, "/") != strings.Join(v2[i].Path, "/") || v1[i].Length != v2[i].Length {
+			return fmt.Errorf("metainfo: hybrid torrent file %d differs: v1 %q (%d bytes), v2 %q (%d bytes)",
+				i, strings.Join(v1[i].Path, "/"), v1[i].Length, strings.Join(v2[i].Path, "/"), v2[i].Length)
+		}
+	}
+	return nil
+}
+
+// validatePieceLayers checks that every file larger than a piece has its
+// piece layer and that the layer hashes up to the file's pieces root.
+func (mi *MetaInfo) validatePieceLayers() error {
+	files, err := mi.Info.FilesV2()
+	if err != nil {
+		return err
+	}
+	pl := mi.Info.PieceLength
+	for _, f := range files {
+		if f.Length <= pl {
+			continue
+		}
+		layer, ok := mi.PieceLayers[f.PiecesRoot]
+		if !ok {
+			return fmt.Errorf("metainfo: piece layer of %q is missing", strings.Join(f.Path, "/"))
+		}
+		hashes, err := splitHashes(layer)
+		if err != nil {
+			return fmt.Errorf("metainfo: piece layer of %q: %w", strings.Join(f.Path, "/"), err)
+		}
+		if want := (f.Length + pl - 1) / pl; int64(len(hashes)) != want {
+			return fmt.Errorf("metainfo: piece layer of %q has %d hashes, want %d", strings.Join(f.Path, "/"), len(hashes), want)
+		}
+		if root := PieceLayerRoot(hashes, pl); string(root[:]) != f.PiecesRoot {
+			return fmt.Errorf("metainfo: piece layer of %q does not match its pieces root", strings.Join(f.Path, "/"))
+		}
+	}
+	return nil
+}
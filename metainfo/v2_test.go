@@ -0,0 +1,228 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// blockHashes returns the SHA-256 hashes of the 16 KiB blocks of data.
+func blockHashes(data []byte) []HashV2 {
+	var hashes []HashV2
+	for i := 0; i < len(data); i += BlockSize {
+		end := i + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		hashes = append(hashes, sha256.Sum256(data[i:end]))
+	}
+	return hashes
+}
+
+// hybrid returns a hybrid torrent of two files, the first one spanning
+// three pieces.
+func hybrid() *MetaInfo {
+	const pieceLength = BlockSize
+	a := bytes.Repeat([]byte("a"), 40000)
+	b := bytes.Repeat([]byte("b"), 100)
+	pad := make([]byte, 3*pieceLength-len(a))
+
+	var pieces []byte
+	v1 := append(append(append([]byte(nil), a...), pad...), b...)
+	for i := 0; i < len(v1); i += pieceLength {
+		end := i + pieceLength
+		if end > len(v1) {
+			end = len(v1)
+		}
+		h := sha1.Sum(v1[i:end])
+		pieces = append(pieces, h[:]...)
+	}
+
+	rootA := FileRoot(blockHashes(a), pieceLength)
+	rootB := FileRoot(blockHashes(b), pieceLength)
+	return &MetaInfo{
+		Info: Info{
+			PieceLength: pieceLength,
+			Pieces:      string(pieces),
+			Name:        "dir",
+			Files: []File{
+				{Length: int64(len(a)), Path: []string{"a"}},
+				{Attr: "p", Length: int64(len(pad)), Path: []string{".pad", "9152"}},
+				{Length: int64(len(b)), Path: []string{"sub", "b"}},
+			},
+			MetaVersion: 2,
+			FileTree: NewFileTree([]FileV2{
+				{Path: []string{"a"}, Length: int64(len(a)), PiecesRoot: string(rootA[:])},
+				{Path: []string{"sub", "b"}, Length: int64(len(b)), PiecesRoot: string(rootB[:])},
+			}),
+		},
+		PieceLayers: map[string]string{
+			string(rootA[:]): joinHashV2(PieceLayer(blockHashes(a), pieceLength)),
+		},
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	mi := hybrid()
+	if err := mi.Validate(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	got, err := Load(&buf)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !got.Info.IsHybrid() {
+		t.Error("loaded torrent is not hybrid")
+	}
+
+	want, _ := mi.Info.FilesV2()
+	files, err := got.Info.FilesV2()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("\ngot: %v \nwant: %v", files, want)
+	}
+	if !reflect.DeepEqual(got.PieceLayers, mi.PieceLayers) {
+		t.Error("piece layers differ after roundtrip")
+	}
+
+	h1, _ := mi.InfoHashV1()
+	h2, _ := got.InfoHashV1()
+	if h1 != h2 {
+		t.Error("got:", h2, "want:", h1)
+	}
+}
+
+func TestV2Only(t *testing.T) {
+	mi := hybrid()
+	mi.Info.Pieces = ""
+	mi.Info.Files = nil
+	if err := mi.Validate(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if mi.Info.HasV1() || !mi.Info.HasV2() {
+		t.Error("got v1:", mi.Info.HasV1(), "v2:", mi.Info.HasV2())
+	}
+	if got := mi.Info.TotalLength(); got != 40100 {
+		t.Error("got:", got, "want:", 40100)
+	}
+}
+
+func TestSingleFileTree(t *testing.T) {
+	// file tree keys are path components and the file entry is under ""
+	input := "d4:infod9:file treed4:filed0:d6:lengthi0eeee12:meta versioni2e4:name4:file12:piece lengthi16384eee"
+	mi, err := Load(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	files, err := mi.Info.FilesV2()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := []FileV2{{Path: []string{"file"}, Length: 0}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("\ngot: %v \nwant: %v", files, want)
+	}
+
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if buf.String() != input {
+		t.Errorf("\ngot: %s \nwant: %s", buf.String(), input)
+	}
+}
+
+func TestValidateV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*MetaInfo)
+	}{
+		{"Meta version", func(mi *MetaInfo) { mi.Info.MetaVersion = 3 }},
+		{"Piece length", func(mi *MetaInfo) { mi.Info.PieceLength = 3 * BlockSize }},
+		{"Missing layer", func(mi *MetaInfo) { mi.PieceLayers = nil }},
+		{"Wrong layer", func(mi *MetaInfo) {
+			for root, layer := range mi.PieceLayers {
+				mi.PieceLayers[root] = layer[32:] + layer[:32]
+			}
+		}},
+		{"Length mismatch", func(mi *MetaInfo) { mi.Info.Files[2].Length = 99 }},
+		{"Path mismatch", func(mi *MetaInfo) { mi.Info.Files[0].Path = []string{"c"} }},
+		{"Mixed entry", func(mi *MetaInfo) {
+			mi.Info.FileTree["sub"].(map[string]interface{})[""] = map[string]interface{}{"length": int64(0)}
+		}},
+		{"Bad root", func(mi *MetaInfo) {
+			mi.Info.FileTree["a"].(map[string]interface{})[""].(map[string]interface{})["pieces root"] = "short"
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mi := hybrid()
+			test.modify(mi)
+			if err := mi.Validate(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+// The expected hashes were computed independently of this package, by
+// hashing 16 KiB blocks with SHA-256 and padding the leaves with zero hashes
+// to a power of two, as BEP 52 describes.
+func TestFileRootKnownAnswer(t *testing.T) {
+	fiveBlocks := make([]byte, 4*BlockSize+100)
+	for i := range fiveBlocks {
+		fiveBlocks[i] = byte(i % 251)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		root  string
+		layer []string // with 32 KiB pieces
+	}{
+		{"Single block", []byte("spam"), "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb", nil},
+		{"Padded pieces", fiveBlocks, "4dc991d3778c61cbdd4974b0589d82c3376934f9540df55d7f00f6d77c990365", []string{
+			"d9e13d0b676ad681164ef0b7b5910d1328ea83a047cad57e619d76bbe3a08525",
+			"e28097eaaa55956702cf8195d1a551dbabb63e3d679b294cf33d506a6b5ef479",
+			"e14fad471eb218a2cdf57962a8c085291743da1470f7c81315b913278aa3df73",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string][]byte{"file": test.data})
+			mi, err := (&Builder{PieceLength: 2 * BlockSize, Version: Version2}).Build(filepath.Join(dir, "file"))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			files, err := mi.Info.FilesV2()
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got := hex.EncodeToString([]byte(files[0].PiecesRoot)); got != test.root {
+				t.Errorf("\ngot: %s \nwant: %s", got, test.root)
+			}
+
+			var layer []string
+			hashes, _ := splitHashes(mi.PieceLayers[files[0].PiecesRoot])
+			for _, h := range hashes {
+				layer = append(layer, hex.EncodeToString(h[:]))
+			}
+			if !reflect.DeepEqual(layer, test.layer) {
+				t.Errorf("\ngot: %v \nwant: %v", layer, test.layer)
+			}
+		})
+	}
+}
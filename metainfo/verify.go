@@ -0,0 +1,292 @@
+package metainfo
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+// Bitfield is a set of piece indices, laid out as in the peer wire
+// protocol: the high bit of the first byte is piece 0.
+type Bitfield []byte
+
+// NewBitfield returns an empty bitfield of n pieces.
+func NewBitfield(n int) Bitfield {
+	return make(Bitfield, (n+7)/8)
+}
+
+// Has reports whether piece i is in the set.
+func (b Bitfield) Has(i int) bool {
+	return b[i/8]&(0x80>>uint(i%8)) != 0
+}
+
+// Set adds piece i to the set.
+func (b Bitfield) Set(i int) {
+	b[i/8] |= 0x80 >> uint(i%8)
+}
+
+// Count returns the number of pieces in the set.
+func (b Bitfield) Count() int {
+	n := 0
+	for _, x := range b {
+		n += bits.OnesCount8(x)
+	}
+	return n
+}
+
+// FileRange is a part of a file covered by a piece.
+type FileRange struct {
+	// Path is the path of the file relative to the base directory, as
+	// returned by Info.FilePath. It is nil for padding files.
+	Path []string
+	// Offset is the position of the range in the file.
+	Offset int64
+	Length int64
+}
+
+// pieceLayout maps the pieces of a torrent to files.
+type pieceLayout struct {
+	pieceLength int64
+	v1          *storage   // v1 content as a single stream, nil for v2
+	v1Paths     [][]string // paths of the v1 files, nil for padding
+	v2          []v2Layout // v2 files, each aligned to a piece boundary
+	numPieces   int
+}
+
+type v2Layout struct {
+	file       FileV2
+	path       []string
+	storage    *storage
+	firstPiece int
+	numPieces  int
+	layer      []HashV2 // nil for files of a single piece
+}
+
+// FilePath returns the path of a file of the torrent relative to the base
+// directory which holds the torrent's content. For a multi-file torrent it
+// is the torrent name followed by the path of the file. It fails for paths
+// which could escape the base directory.
+func (info *Info) FilePath(path []string) ([]string, error) {
+	if info.isSingleFile() {
+		path = []string{info.Name}
+	} else {
+		path = append([]string{info.Name}, path...)
+	}
+	for _, c := range path {
+		if c == "" || c == "." || c == ".." || strings.ContainsAny(c, `/\`) {
+			return nil, fmt.Errorf("metainfo: unsafe path %q", strings.Join(path, "/"))
+		}
+	}
+	return path, nil
+}
+
+// isSingleFile reports whether the content is a single file named by the
+// torrent name. A v2 torrent is single-file when its file tree holds
+// a single file at the root, named by the torrent name; a directory with
+// a single file has its own name under the torrent name.
+func (info *Info) isSingleFile() bool {
+	if info.HasV1() {
+		return info.Length != nil
+	}
+	files, err := info.FilesV2()
+	return err == nil && len(files) == 1 && len(files[0].Path) == 1 && files[0].Path[0] == info.Name
+}
+
+// layout validates mi, so that metainfos which were built by the caller
+// rather than loaded cannot index past their piece hashes.
+func (mi *MetaInfo) layout(dir string) (*pieceLayout, error) {
+	if err := mi.Validate(); err != nil {
+		return nil, err
+	}
+	info := &mi.Info
+	l := &pieceLayout{pieceLength: info.PieceLength}
+
+	if info.HasV1() {
+		l.v1 = &storage{}
+		files := info.Files
+		if info.Length != nil {
+			files = []File{{Length: *info.Length}}
+		}
+		for _, f := range files {
+			if f.IsPadding() {
+				l.v1.add("", f.Length)
+				l.v1Paths = append(l.v1Paths, nil)
+				continue
+			}
+			path, err := info.FilePath(f.Path)
+			if err != nil {
+				return nil, err
+			}
+			l.v1.add(filePath(dir, path), f.Length)
+			l.v1Paths = append(l.v1Paths, path)
+		}
+		l.numPieces = info.NumPieces()
+	}
+
+	if info.HasV2() {
+		files, err := info.FilesV2()
+		if err != nil {
+			return nil, err
+		}
+		piece := 0
+		for _, f := range files {
+			path, err := info.FilePath(f.Path)
+			if err != nil {
+				return nil, err
+			}
+			s := &storage{}
+			s.add(filePath(dir, path), f.Length)
+			n := int((f.Length + l.pieceLength - 1) / l.pieceLength)
+			fl := v2Layout{file: f, path: path, storage: s, firstPiece: piece, numPieces: n}
+			if n > 1 {
+				if fl.layer, err = splitHashes(mi.PieceLayers[f.PiecesRoot]); err != nil {
+					return nil, err
+				}
+				if len(fl.layer) != n {
+					return nil, fmt.Errorf("metainfo: piece layer of %q has %d hashes, want %d", strings.Join(path, "/"), len(fl.layer), n)
+				}
+			}
+			l.v2 = append(l.v2, fl)
+			// v2 files are aligned to pieces, so are hybrid v1 files
+			piece += n
+		}
+		if !info.HasV1() {
+			l.numPieces = piece
+		}
+	}
+	return l, nil
+}
+
+// NumPieces returns the number of pieces of the torrent, as verified by
+// Verify. Unlike Info.NumPieces, it counts the pieces of a v2-only torrent,
+// in which every file starts a new piece.
+func (mi *MetaInfo) NumPieces() (int, error) {
+	l, err := mi.layout("")
+	if err != nil {
+		return 0, err
+	}
+	return l.numPieces, nil
+}
+
+// PieceRanges returns the file ranges covered by piece i. A piece of a
+// multi-file v1 torrent may cross file boundaries; a v2 piece never does.
+func (mi *MetaInfo) PieceRanges(i int) ([]FileRange, error) {
+	l, err := mi.layout("")
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= l.numPieces {
+		return nil, fmt.Errorf("metainfo: piece %d out of range", i)
+	}
+	return l.ranges(i), nil
+}
+
+func (l *pieceLayout) ranges(i int) []FileRange {
+	var ranges []FileRange
+	if l.v1 != nil {
+		start := int64(i) * l.pieceLength
+		end := start + pieceSize(l.v1.size, l.pieceLength, i)
+		for k, f := range l.v1.files {
+			if f.offset+f.length <= start || f.offset >= end || f.length == 0 {
+				continue
+			}
+			from, to := start, end
+			if from < f.offset {
+				from = f.offset
+			}
+			if to > f.offset+f.length {
+				to = f.offset + f.length
+			}
+			ranges = append(ranges, FileRange{Path: l.v1Paths[k], Offset: from - f.offset, Length: to - from})
+		}
+		return ranges
+	}
+	for _, f := range l.v2 {
+		if i >= f.firstPiece && i < f.firstPiece+f.numPieces {
+			p := i - f.firstPiece
+			return []FileRange{{
+				Path:   f.path,
+				Offset: int64(p) * l.pieceLength,
+				Length: pieceSize(f.file.Length, l.pieceLength, p),
+			}}
+		}
+	}
+	return nil
+}
+
+// VerifyOptions configures Verify.
+type VerifyOptions struct {
+	// Workers is the number of goroutines hashing pieces. Defaults to the
+	// number of CPUs.
+	Workers int
+	// Progress, if set, is called after each piece is checked with the
+	// number of checked pieces and the total. Calls are serialized.
+	Progress func(done, total int)
+}
+
+// Verify checks the data in dir against the piece hashes of mi and returns
+// the set of good pieces. The content is expected at the paths returned by
+// Info.FilePath relative to dir. Missing or short files make the pieces
+// which cover them bad. Pieces of a v1 torrent are checked with SHA-1,
+// pieces of a v2 torrent with the merkle trees of SHA-256; a piece of
+// a hybrid torrent is good only if it passes both checks.
+func (mi *MetaInfo) Verify(dir string, opts VerifyOptions) (Bitfield, error) {
+	l, err := mi.layout(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	good := make([]bool, l.numPieces)
+	var (
+		mu   sync.Mutex
+		done int
+	)
+	err = parallel(opts.Workers, l.numPieces, func(i int) error {
+		good[i] = l.verify(mi, i)
+		if opts.Progress != nil {
+			mu.Lock()
+			done++
+			opts.Progress(done, l.numPieces)
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := NewBitfield(l.numPieces)
+	for i, ok := range good {
+		if ok {
+			b.Set(i)
+		}
+	}
+	return b, nil
+}
+
+// verify reports whether piece i is good.
+func (l *pieceLayout) verify(mi *MetaInfo, i int) bool {
+	if l.v1 != nil {
+		h, err := hashPieceV1(l.v1, l.pieceLength, i)
+		if err != nil || string(h[:]) != string(mi.Info.PieceHash(i)) {
+			return false
+		}
+	}
+	for _, f := range l.v2 {
+		if i < f.firstPiece || i >= f.firstPiece+f.numPieces {
+			continue
+		}
+		p := i - f.firstPiece
+		blocks, err := hashPieceBlocks(f.storage, l.pieceLength, p)
+		if err != nil {
+			return false
+		}
+		if f.layer == nil {
+			root := BlockRoot(blocks)
+			return string(root[:]) == f.file.PiecesRoot
+		}
+		return pieceRootV2(blocks, l.pieceLength) == f.layer[p]
+	}
+	return true
+}
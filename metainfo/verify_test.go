@@ -0,0 +1,157 @@
+package metainfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBitfield(t *testing.T) {
+	b := NewBitfield(10)
+	b.Set(0)
+	b.Set(9)
+	if !bytes.Equal(b, []byte{0x80, 0x40}) {
+		t.Errorf("got: %08b", b)
+	}
+	if !b.Has(9) || b.Has(8) || b.Count() != 2 {
+		t.Error("got:", b.Has(9), b.Has(8), b.Count())
+	}
+}
+
+func TestPieceRanges(t *testing.T) {
+	mi := &MetaInfo{Info: Info{
+		Name:        "dir",
+		PieceLength: 4,
+		Pieces:      string(make([]byte, 3*HashSize)),
+		Files: []File{
+			{Length: 3, Path: []string{"a"}},
+			{Length: 0, Path: []string{"empty"}},
+			{Length: 6, Path: []string{"b", "c"}},
+		},
+	}}
+
+	tests := []struct {
+		piece int
+		want  []FileRange
+	}{
+		{0, []FileRange{{Path: []string{"dir", "a"}, Offset: 0, Length: 3}, {Path: []string{"dir", "b", "c"}, Offset: 0, Length: 1}}},
+		{1, []FileRange{{Path: []string{"dir", "b", "c"}, Offset: 1, Length: 4}}},
+		{2, []FileRange{{Path: []string{"dir", "b", "c"}, Offset: 5, Length: 1}}},
+	}
+	for _, test := range tests {
+		got, err := mi.PieceRanges(test.piece)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("piece %d\ngot: %+v \nwant: %+v", test.piece, got, test.want)
+		}
+	}
+	if _, err := mi.PieceRanges(3); err == nil {
+		t.Error("no error for piece out of range")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	files := map[string][]byte{
+		"a":     bytes.Repeat([]byte("a"), 40000),
+		"sub/b": bytes.Repeat([]byte("b"), 100),
+	}
+	base := filepath.Dir(writeFiles(t, files))
+
+	for _, version := range []Version{Version1, Version2, VersionHybrid} {
+		mi, err := (&Builder{PieceLength: BlockSize, Version: version}).Build(filepath.Join(base, "dir"))
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		var calls int
+		got, err := mi.Verify(base, VerifyOptions{Progress: func(done, total int) { calls++ }})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		n := mi.Info.NumPieces()
+		if version == Version2 {
+			n = 4
+		}
+		if got, err := mi.NumPieces(); err != nil || got != n {
+			t.Errorf("version %d: got %d pieces, want %d", version, got, n)
+		}
+		if got.Count() != n || calls != n {
+			t.Errorf("version %d: got %d good pieces and %d calls, want %d", version, got.Count(), calls, n)
+		}
+	}
+
+	// metadata received from peers has no piece layers
+	noLayers := hybrid()
+	noLayers.PieceLayers = nil
+	if _, err := noLayers.Verify(base, VerifyOptions{}); err == nil {
+		t.Error("no error for missing piece layers")
+	}
+
+	// corrupt the second piece of a and remove b
+	f, err := os.OpenFile(filepath.Join(base, "dir", "a"), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte("x"), BlockSize+1)
+	f.Close()
+	if err := os.Remove(filepath.Join(base, "dir", "sub", "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	mi := hybrid()
+	got, err := mi.Verify(base, VerifyOptions{Workers: 2})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := Bitfield{0xa0} // pieces 0 and 2 of 4
+	if !bytes.Equal(got, want) {
+		t.Errorf("got: %08b want: %08b", got, want)
+	}
+}
+
+func TestFilePathUnsafe(t *testing.T) {
+	info := &Info{Name: "dir", Files: []File{}}
+	for _, path := range [][]string{{".."}, {"a", ""}, {"a/b"}, {`a\b`}} {
+		if _, err := info.FilePath(path); err == nil {
+			t.Error("no error for", path)
+		}
+	}
+	info.Name = ".."
+	if _, err := info.FilePath([]string{"a"}); err == nil {
+		t.Error("no error for unsafe name")
+	}
+}
+
+func TestVerifyOneFileDir(t *testing.T) {
+	dir := writeFiles(t, map[string][]byte{"file": bytes.Repeat([]byte("f"), 3*BlockSize)})
+
+	for _, version := range []Version{Version1, Version2, VersionHybrid} {
+		mi, err := (&Builder{PieceLength: BlockSize, Version: version}).Build(dir)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		got, err := mi.Verify(filepath.Dir(dir), VerifyOptions{})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if got.Count() != 3 {
+			t.Errorf("version %d: got %d good pieces, want 3", version, got.Count())
+		}
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	// a metainfo built by the caller, with fewer piece hashes than pieces
+	length := int64(3 * BlockSize)
+	mi := &MetaInfo{Info: Info{PieceLength: BlockSize, Pieces: string(make([]byte, HashSize)), Name: "file", Length: &length}}
+	if _, err := mi.Verify(t.TempDir(), VerifyOptions{}); err == nil {
+		t.Error("no error for too few piece hashes")
+	}
+	if _, err := mi.PieceRanges(2); err == nil {
+		t.Error("no error for too few piece hashes")
+	}
+}
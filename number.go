@@ -0,0 +1,33 @@
+package bencode
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Number is the decimal form of a bencoded integer of any size. A Decoder
+// stores integers as Number when decoding into interface{} with UseNumber.
+// Number is encoded as an integer.
+type Number string
+
+// String returns the decimal form of the number.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// BigInt returns the number as a *big.Int. ok is false if n is not a valid
+// decimal integer.
+func (n Number) BigInt() (i *big.Int, ok bool) {
+	return new(big.Int).SetString(string(n), 10)
+}
+
+var (
+	numberType = reflect.TypeOf(Number(""))
+	bigIntType = reflect.TypeOf(big.Int{})
+)
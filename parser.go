@@ -24,6 +24,11 @@ type Parser struct {
 	reader *bufio.Reader
 	offset int64
 	// tree   *Value
+
+	// raw holds the bytes consumed by the current Parse call if capturing
+	// is enabled. Parsed dicts keep a subslice of it.
+	capture bool
+	raw     []byte
 }
 
 // NewParser returns a new parser
@@ -32,12 +37,27 @@ func NewParser(r io.Reader) *Parser {
 	return &Parser{reader: br}
 }
 
+// CaptureRaw enables or disables capturing of the original bytes of the
+// parsed dicts. When enabled, Dict.Raw returns the exact bytes each parsed
+// dict was read from, at the cost of keeping a copy of the parsed data.
+func (p *Parser) CaptureRaw(enable bool) {
+	p.capture = enable
+}
+
 // Parse parses.
 func (p *Parser) Parse() (v Value, err error) {
+	p.raw = nil
 	v, err = p.parseValue()
 	return
 }
 
+// record appends the consumed bytes to the capture buffer.
+func (p *Parser) record(s string) {
+	if p.capture {
+		p.raw = append(p.raw, s...)
+	}
+}
+
 func (p *Parser) parseValue() (Value, error) {
 	bs, err := p.reader.Peek(1)
 	if err != nil {
@@ -70,6 +90,7 @@ func (p *Parser) parseInt() (Int, error) {
 	// read until delimeter 'e'
 	s, err := p.reader.ReadString('e')
 	p.offset += int64(len(s))
+	p.record(s)
 	if err != nil {
 		return Int(0), &ErrSyntax{pos: p.offset, msg: "cannot find the end delimeter of the integer"}
 	}
@@ -93,10 +114,12 @@ func (p *Parser) parseString() (String, error) {
 	}
 	s = s[:len(s)-1] // trim delimeter ':'
 	length, err = strconv.ParseInt(s, 10, 64)
-	if err != nil {
+	if err != nil || length < 0 {
 		return String(""), &ErrSyntax{pos: p.offset, msg: fmt.Sprintf("cannot parse string length '%v' as integer", s)}
 	}
 	p.offset += int64(len(s) + 1)
+	p.record(s)
+	p.record(":")
 
 	// parse string value
 	bs := make([]byte, length)
@@ -105,6 +128,7 @@ func (p *Parser) parseString() (String, error) {
 		return String(""), &ErrSyntax{pos: p.offset, msg: "string length is wrong"}
 	}
 	p.offset += int64(n)
+	p.record(string(bs))
 
 	return String(bs), nil
 }
@@ -133,6 +157,7 @@ ParseValuesLoop:
 
 func (p *Parser) parseDict() (*Dict, error) {
 	dict := NewDict()
+	start := len(p.raw)
 
 	if err := p.skipDelimeter(); err != nil {
 		return dict, err
@@ -162,6 +187,10 @@ ParseItemsLoop:
 		dict.Set(key, value)
 	}
 
+	if p.capture {
+		end := len(p.raw)
+		dict.raw = p.raw[start:end:end]
+	}
 	return dict, nil
 }
 
@@ -172,6 +201,7 @@ func (p *Parser) parseEndOfValue() (Value, error) {
 		return nil, &ErrSyntax{pos: p.offset, msg: "unexpected token"}
 	}
 	p.offset++
+	p.record("e")
 	return nil, errValueEnd
 }
 
@@ -184,6 +214,7 @@ func (p *Parser) skipDelimeter() error {
 		return &ErrSyntax{pos: p.offset, msg: "unexpected token"}
 	}
 	p.offset++
+	p.record(string(b))
 	if b == 'e' {
 		return errValueEnd
 	}
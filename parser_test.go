@@ -27,6 +27,7 @@ func TestParse(t *testing.T) {
 		// Dict
 		{"Dict/Simple", `d4:spam4:eggse`, NewDict([]DictItem{{String("spam"), String("eggs")}}...)},
 		{"Dict/Three items", `d4:spam4:eggs3:key3:val6:answeri42ee`, NewDict([]DictItem{{String("spam"), String("eggs")}, {String("key"), String("val")}, {String("answer"), Int(42)}}...)},
+		{"Dict/Empty key", `d0:i1ee`, NewDict([]DictItem{{String(""), Int(1)}}...)},
 		{"Dict/Nested dict", `d4:spamd4:spam4:eggsee`, NewDict([]DictItem{{String("spam"), NewDict([]DictItem{{String("spam"), String("eggs")}}...)}}...)},
 	}
 
@@ -44,3 +45,27 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCaptureRaw(t *testing.T) {
+	input := `d1:zi03e1:ad1:b0:ee`
+	parser := NewParser(strings.NewReader(input))
+	parser.CaptureRaw(true)
+	got, err := parser.Parse()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	dict := got.(*Dict)
+	if string(dict.Raw()) != input {
+		t.Error("got:", string(dict.Raw()), "want:", input)
+	}
+	nested := dict.Get(String("a")).(*Dict)
+	if want := `d1:b0:e`; string(nested.Raw()) != want {
+		t.Error("got:", string(nested.Raw()), "want:", want)
+	}
+
+	dict.Set(String("a"), Int(1))
+	if dict.Raw() != nil {
+		t.Error("got:", string(dict.Raw()), "want: nil after modification")
+	}
+}
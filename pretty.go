@@ -0,0 +1,227 @@
+package bencode
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrettyOptions configures the output of Pretty.
+type PrettyOptions struct {
+	// Indent is used to indent nested values. Defaults to two spaces.
+	Indent string
+	// MaxString is the number of bytes of a string to show before it is
+	// truncated with an ellipsis. Defaults to 64; negative means no limit.
+	MaxString int
+	// Offsets adds a column with the byte offset of each value in the
+	// bencoded form of the printed tree.
+	Offsets bool
+}
+
+const defaultPrettyMaxString = 64
+
+// prettyMaxPieces is the number of bytes shown of the concatenated hashes
+// in pieces and piece layers, whatever MaxString is.
+const prettyMaxPieces = 20
+
+// Pretty writes an indented, annotated representation of v to w. Each
+// container is annotated with the number of items, text strings are quoted
+// and binary strings are shown as hex along with their length. The values of
+// pieces and piece layers are always truncated.
+func Pretty(w io.Writer, v Value, opts PrettyOptions) error {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	if opts.MaxString == 0 {
+		opts.MaxString = defaultPrettyMaxString
+	}
+	p := &prettyPrinter{w: bufio.NewWriter(w), opts: opts}
+	p.value(v, "", 0, 0, 0, opts.MaxString)
+	return p.w.Flush()
+}
+
+type prettyPrinter struct {
+	w    *bufio.Writer
+	opts PrettyOptions
+}
+
+// value prints v on a line prefixed with the label. The line offset is the
+// position of the label, which is the position of v unless it is a dict key.
+// Strings in v are truncated to max bytes.
+func (p *prettyPrinter) value(v Value, label string, depth int, lineOffset, offset int64, max int) {
+	if p.opts.Offsets {
+		fmt.Fprintf(p.w, "%8d  ", lineOffset)
+	}
+	p.w.WriteString(strings.Repeat(p.opts.Indent, depth))
+	p.w.WriteString(label)
+
+	switch v := v.(type) {
+	case List:
+		fmt.Fprintf(p.w, "list (%s)\n", plural(len(v), "item"))
+		offset++ // 'l'
+		for _, item := range v {
+			p.value(item, "", depth+1, offset, offset, max)
+			offset += int64(item.EncodedLen())
+		}
+	case *Dict:
+		if v == nil {
+			p.w.WriteString("<nil>\n")
+			return
+		}
+		fmt.Fprintf(p.w, "dict (%s)\n", plural(len(v.keys), "key"))
+		offset++ // 'd'
+		for _, key := range v.keys {
+			val := v.m[key]
+			valOffset := offset + int64(key.EncodedLen())
+			p.value(val, formatKey(key, p.opts.MaxString)+": ", depth+1, offset, valOffset, valueMax(key, max))
+			offset = valOffset + int64(val.EncodedLen())
+		}
+	default:
+		p.w.WriteString(formatScalar(v, max))
+		p.w.WriteByte('\n')
+	}
+}
+
+// valueMax returns the truncation limit for the value of key in a dict whose
+// strings are limited to max bytes.
+func valueMax(key String, max int) int {
+	if key != "pieces" && key != "piece layers" {
+		return max
+	}
+	if max >= 0 && max < prettyMaxPieces {
+		return max
+	}
+	return prettyMaxPieces
+}
+
+// plural returns n followed by noun, in plural form unless n is one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// formatScalar returns a single line representation of Int and String.
+func formatScalar(v Value, max int) string {
+	switch v := v.(type) {
+	case Int:
+		return strconv.FormatInt(int64(v), 10)
+	case String:
+		return formatString(v, max)
+	case nil:
+		return "<nil>"
+	}
+	return fmt.Sprintf("<%T>", v)
+}
+
+func formatString(s String, max int) string {
+	truncated := max >= 0 && len(s) > max
+	shown := s
+	if truncated {
+		shown = s[:max]
+	}
+	if utf8.ValidString(string(s)) {
+		if truncated {
+			// do not cut a rune in half
+			for len(shown) > 0 && !utf8.ValidString(string(shown)) {
+				shown = shown[:len(shown)-1]
+			}
+			return fmt.Sprintf("%s… (%s)", strconv.Quote(string(shown)), plural(len(s), "byte"))
+		}
+		return strconv.Quote(string(s))
+	}
+	h := hex.EncodeToString([]byte(shown))
+	if truncated {
+		h += "…"
+	}
+	return fmt.Sprintf("<%s> %s", plural(len(s), "byte"), h)
+}
+
+// formatKey returns a dict key as is when it is a plain word, and formats it
+// as a string otherwise.
+func formatKey(key String, max int) string {
+	if key == "" || strings.IndexFunc(string(key), func(r rune) bool {
+		return r <= ' ' || r == '"' || r == ':' || r >= utf8.RuneSelf
+	}) >= 0 {
+		return formatString(key, max)
+	}
+	return string(key)
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs print the list on a
+// single line, %+v prints it as Pretty does with the default options.
+func (l List) Format(f fmt.State, verb rune) {
+	formatValue(f, verb, l)
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs print the dict on a
+// single line with the keys in order, %+v prints it as Pretty does with the
+// default options.
+func (d *Dict) Format(f fmt.State, verb rune) {
+	formatValue(f, verb, d)
+}
+
+// formatValue prints v for the verbs of Format. Other verbs are reported
+// the way fmt reports them.
+func formatValue(f fmt.State, verb rune, v Value) {
+	var s string
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		var sb strings.Builder
+		Pretty(&sb, v, PrettyOptions{})
+		s = strings.TrimSuffix(sb.String(), "\n")
+	case verb == 'v' || verb == 's':
+		s = string(appendCompact(nil, v, defaultPrettyMaxString))
+	default:
+		fmt.Fprintf(f, "%%!%c(%T=%s)", verb, v, appendCompact(nil, v, defaultPrettyMaxString))
+		return
+	}
+	if width, ok := f.Width(); ok {
+		if pad := width - utf8.RuneCountInString(s); pad > 0 {
+			if f.Flag('-') {
+				s += strings.Repeat(" ", pad)
+			} else {
+				s = strings.Repeat(" ", pad) + s
+			}
+		}
+	}
+	io.WriteString(f, s)
+}
+
+// appendCompact appends a single line representation of v to b, truncating
+// strings to max bytes.
+func appendCompact(b []byte, v Value, max int) []byte {
+	switch v := v.(type) {
+	case List:
+		b = append(b, '[')
+		for i, item := range v {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = appendCompact(b, item, max)
+		}
+		b = append(b, ']')
+	case *Dict:
+		if v == nil {
+			return append(b, "<nil>"...)
+		}
+		b = append(b, '{')
+		for i, key := range v.keys {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = append(b, formatKey(key, defaultPrettyMaxString)...)
+			b = append(b, ": "...)
+			b = appendCompact(b, v.m[key], valueMax(key, max))
+		}
+		b = append(b, '}')
+	default:
+		b = append(b, formatScalar(v, max)...)
+	}
+	return b
+}
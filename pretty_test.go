@@ -0,0 +1,108 @@
+package bencode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPretty(t *testing.T) {
+	tree := NewDict([]DictItem{
+		{String("announce"), String("http://tracker/announce")},
+		{String("info"), NewDict([]DictItem{
+			{String("length"), Int(42)},
+			{String("pieces"), String(strings.Repeat("\xab", 40))},
+		}...)},
+		{String("list"), List{String("spam"), Int(-1)}},
+	}...)
+
+	t.Run("Default", func(t *testing.T) {
+		want := `dict (3 keys)
+  announce: "http://tracker/announce"
+  info: dict (2 keys)
+    length: 42
+    pieces: <40 bytes> ` + strings.Repeat("ab", 20) + `…
+  list: list (2 items)
+    "spam"
+    -1
+`
+		var sb strings.Builder
+		if err := Pretty(&sb, tree, PrettyOptions{}); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if got := sb.String(); got != want {
+			t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Truncate and offsets", func(t *testing.T) {
+		want := `       0  dict (3 keys)
+       1    announce: "http:/"… (23 bytes)
+      37    info: dict (2 keys)
+      44      length: 42
+      56      pieces: <40 bytes> abababababab…
+     108    list: list (2 items)
+     115      "spam"
+     121      -1
+`
+		var sb strings.Builder
+		if err := Pretty(&sb, tree, PrettyOptions{MaxString: 6, Offsets: true}); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if got := sb.String(); got != want {
+			t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestPrettyPiecesNoLimit(t *testing.T) {
+	tree := NewDict([]DictItem{
+		{String("name"), String(strings.Repeat("x", 100))},
+		{String("piece layers"), NewDict([]DictItem{
+			{String(strings.Repeat("\xcd", 32)), String(strings.Repeat("\xef", 64))},
+		}...)},
+	}...)
+	want := `dict (2 keys)
+  name: "` + strings.Repeat("x", 100) + `"
+  "piece layers": dict (1 key)
+    <32 bytes> ` + strings.Repeat("cd", 32) + `: <64 bytes> ` + strings.Repeat("ef", 20) + `…
+`
+	var sb strings.Builder
+	if err := Pretty(&sb, tree, PrettyOptions{MaxString: -1}); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		val    Value
+		want   string
+	}{
+		{"List", "%v", List{String("spam"), Int(42)}, `["spam" 42]`},
+		{"Dict", "%v", NewDict([]DictItem{{String("z"), Int(1)}, {String("a b"), String("\xff")}}...), `{z: 1, "a b": <1 byte> ff}`},
+		{"Dict/Plus", "%+v", NewDict([]DictItem{{String("spam"), List{Int(1)}}}...), "dict (1 key)\n  spam: list (1 item)\n    1"},
+		{"Dict/Pieces", "%v", NewDict([]DictItem{{String("pieces"), String(strings.Repeat("\xab", 21))}}...), "{pieces: <21 bytes> " + strings.Repeat("ab", 20) + "…}"},
+		{"List/String", "%s", List{String("spam")}, `["spam"]`},
+		{"List/Width", "%10v|%-10v|", List{Int(42)}, "      [42]|[42]      |"},
+		{"List/BadVerb", "%d", List{Int(42)}, "%!d(bencode.List=[42])"},
+		{"Dict/BadVerb", "%q", NewDict(), "%!q(*bencode.Dict={})"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := []interface{}{test.val}
+			if strings.Count(test.format, "%") == 2 {
+				args = append(args, test.val)
+			}
+			got := fmt.Sprintf(test.format, args...)
+			if got != test.want {
+				t.Errorf("\ngot: %s \nwant: %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package bencode
+
+import "math"
+
+// Scanner reads bencoded data token by token without building a Value tree.
+// Strings are returned as subslices of the scanned data, so scanning does
+// not allocate. Scanner is meant for code which decodes known layouts
+// directly, such as the methods generated by cmd/bencodegen.
+type Scanner struct {
+	data []byte
+	pos  int
+}
+
+// NewScanner returns a scanner of data.
+func NewScanner(data []byte) *Scanner {
+	return &Scanner{data: data}
+}
+
+// Offset returns the number of bytes consumed so far.
+func (s *Scanner) Offset() int {
+	return s.pos
+}
+
+// Peek returns the first byte of the next token without consuming it: 'i',
+// 'l', 'd' or 'e', or a digit for a string. It returns 0 at the end of the
+// data.
+func (s *Scanner) Peek() byte {
+	if s.pos >= len(s.data) {
+		return 0
+	}
+	return s.data[s.pos]
+}
+
+func (s *Scanner) errorf(msg string) error {
+	return &ErrSyntax{pos: int64(s.pos), msg: msg}
+}
+
+func (s *Scanner) expect(b byte) error {
+	if s.pos >= len(s.data) {
+		return s.errorf("unexpected end of data")
+	}
+	if s.data[s.pos] != b {
+		return s.errorf("unexpected token")
+	}
+	s.pos++
+	return nil
+}
+
+// number reads a decimal integer up to the delimiter.
+func (s *Scanner) number(delim byte) (int64, error) {
+	start := s.pos
+	neg := s.pos < len(s.data) && s.data[s.pos] == '-'
+	if neg {
+		s.pos++
+	}
+	var n uint64
+	digits := 0
+	for ; s.pos < len(s.data) && s.data[s.pos] != delim; s.pos++ {
+		c := s.data[s.pos]
+		if c < '0' || c > '9' {
+			return 0, s.errorf("invalid digit in integer")
+		}
+		if n > (math.MaxUint64-9)/10 {
+			return 0, &ErrSyntax{pos: int64(start), msg: "integer out of range"}
+		}
+		n = n*10 + uint64(c-'0')
+		digits++
+	}
+	if s.pos >= len(s.data) {
+		return 0, s.errorf("unexpected end of data")
+	}
+	if digits == 0 {
+		return 0, &ErrSyntax{pos: int64(start), msg: "integer has no digits"}
+	}
+	s.pos++ // delimiter
+	if neg {
+		if n > -math.MinInt64 {
+			return 0, &ErrSyntax{pos: int64(start), msg: "integer out of range"}
+		}
+		return -int64(n), nil
+	}
+	if n > math.MaxInt64 {
+		return 0, &ErrSyntax{pos: int64(start), msg: "integer out of range"}
+	}
+	return int64(n), nil
+}
+
+// ReadInt reads an integer.
+func (s *Scanner) ReadInt() (int64, error) {
+	if err := s.expect('i'); err != nil {
+		return 0, err
+	}
+	return s.number('e')
+}
+
+// readNumber reads an integer of any size and returns its decimal form,
+// a subslice of the scanned data.
+func (s *Scanner) readNumber() ([]byte, error) {
+	if err := s.expect('i'); err != nil {
+		return nil, err
+	}
+	start := s.pos
+	if s.Peek() == '-' {
+		s.pos++
+	}
+	digits := 0
+	for ; s.pos < len(s.data) && s.data[s.pos] != 'e'; s.pos++ {
+		if c := s.data[s.pos]; c < '0' || c > '9' {
+			return nil, s.errorf("invalid digit in integer")
+		}
+		digits++
+	}
+	if s.pos >= len(s.data) {
+		return nil, s.errorf("unexpected end of data")
+	}
+	if digits == 0 {
+		return nil, &ErrSyntax{pos: int64(start), msg: "integer has no digits"}
+	}
+	s.pos++ // delimiter
+	return s.data[start : s.pos-1 : s.pos-1], nil
+}
+
+// ReadString reads a string. The result is a subslice of the scanned data
+// and must be copied to be retained past modifications of the data.
+func (s *Scanner) ReadString() ([]byte, error) {
+	if c := s.Peek(); c == '-' {
+		return nil, s.errorf("negative string length")
+	} else if c < '0' || c > '9' {
+		if c == 0 {
+			return nil, s.errorf("unexpected end of data")
+		}
+		return nil, s.errorf("unexpected token")
+	}
+	n, err := s.number(':')
+	if err != nil {
+		return nil, err
+	}
+	if n > int64(len(s.data)-s.pos) {
+		return nil, s.errorf("string length is wrong")
+	}
+	b := s.data[s.pos : s.pos+int(n) : s.pos+int(n)]
+	s.pos += int(n)
+	return b, nil
+}
+
+// ReadListStart reads the start of a list. The items follow while More
+// reports true, then ReadEnd reads the end of the list.
+func (s *Scanner) ReadListStart() error {
+	return s.expect('l')
+}
+
+// ReadDictStart reads the start of a dict. Key-value pairs follow while
+// More reports true, then ReadEnd reads the end of the dict.
+func (s *Scanner) ReadDictStart() error {
+	return s.expect('d')
+}
+
+// More reports whether the current list or dict has more items. It reports
+// true at the end of the data, so that reading the next item fails.
+func (s *Scanner) More() bool {
+	return s.Peek() != 'e'
+}
+
+// ReadEnd reads the end of a list or dict.
+func (s *Scanner) ReadEnd() error {
+	return s.expect('e')
+}
+
+// Skip reads the next value and discards it.
+func (s *Scanner) Skip() error {
+	var err error
+	switch s.Peek() {
+	case 'i':
+		_, err = s.ReadInt()
+	case 'l':
+		s.pos++
+		for err == nil && s.More() {
+			err = s.Skip()
+		}
+		if err == nil {
+			err = s.ReadEnd()
+		}
+	case 'd':
+		s.pos++
+		for err == nil && s.More() {
+			if _, err = s.ReadString(); err == nil {
+				err = s.Skip()
+			}
+		}
+		if err == nil {
+			err = s.ReadEnd()
+		}
+	case 0:
+		err = s.errorf("unexpected end of data")
+	default:
+		_, err = s.ReadString()
+	}
+	return err
+}
+
+// ReadRaw reads the next value and returns its bencoded form, a subslice of
+// the scanned data.
+func (s *Scanner) ReadRaw() ([]byte, error) {
+	start := s.pos
+	if err := s.Skip(); err != nil {
+		return nil, err
+	}
+	return s.data[start:s.pos:s.pos], nil
+}
+
+// Finish returns an error if there is data left after the scanned value.
+func (s *Scanner) Finish() error {
+	if s.pos != len(s.data) {
+		return s.errorf("unexpected data after value")
+	}
+	return nil
+}
+
+// Valid reports whether data is a single valid bencoded value.
+func Valid(data []byte) bool {
+	s := NewScanner(data)
+	return s.Skip() == nil && s.Finish() == nil
+}
@@ -0,0 +1,136 @@
+package bencode
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScannerReadInt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int64
+		ok    bool
+	}{
+		{"Zero", "i0e", 0, true},
+		{"Negative", "i-42e", -42, true},
+		{"Max", "i9223372036854775807e", math.MaxInt64, true},
+		{"Min", "i-9223372036854775808e", math.MinInt64, true},
+		{"Overflow", "i9223372036854775808e", 0, false},
+		{"Huge", "i99999999999999999999999e", 0, false},
+		{"Empty", "ie", 0, false},
+		{"Minus only", "i-e", 0, false},
+		{"Bad digit", "i1x2e", 0, false},
+		{"Unterminated", "i12", 0, false},
+		{"Not an int", "4:spam", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewScanner([]byte(test.input)).ReadInt()
+			if (err == nil) != test.ok {
+				t.Fatal("got:", err, "want ok:", test.ok)
+			}
+			if got != test.want {
+				t.Error("got:", got, "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestScannerReadString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		ok    bool
+	}{
+		{"Empty", "0:", "", true},
+		{"Binary", "3:\x00\xff:", "\x00\xff:", true},
+		{"Short", "5:spam", "", false},
+		{"Negative", "-1:", "", false},
+		{"No colon", "4spam", "", false},
+		{"End", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewScanner([]byte(test.input)).ReadString()
+			if (err == nil) != test.ok {
+				t.Fatal("got:", err, "want ok:", test.ok)
+			}
+			if string(got) != test.want {
+				t.Error("got:", string(got), "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestScannerDict(t *testing.T) {
+	s := NewScanner([]byte("d1:ai1e1:bld1:xi1eee1:c3:abce"))
+	if err := s.ReadDictStart(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var keys []string
+	var raw string
+	for s.More() {
+		key, err := s.ReadString()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		keys = append(keys, string(key))
+		if string(key) == "b" {
+			v, err := s.ReadRaw()
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			raw = string(v)
+		} else if err := s.Skip(); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if err := s.ReadEnd(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := s.Finish(); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if got := len(keys); got != 3 || raw != "ld1:xi1eee" {
+		t.Error("got:", keys, raw, "want:", "[a b c] ld1:xi1eee")
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"i1e", true},
+		{"le", true},
+		{"d1:ali1eee", true},
+		{"", false},
+		{"i1ei2e", false},
+		{"l", false},
+		{"di1ei2ee", false},
+		{"x", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if got := Valid([]byte(test.input)); got != test.want {
+				t.Error("got:", got, "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestScannerNoAlloc(t *testing.T) {
+	data := []byte("d4:spami42e4:eggsl3:abc3:defe5:extrad1:xi1eee")
+	allocs := testing.AllocsPerRun(100, func() {
+		s := Scanner{data: data}
+		s.Skip()
+	})
+	if allocs != 0 {
+		t.Error("got:", allocs, "allocations, want: 0")
+	}
+}
@@ -0,0 +1,313 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parse parses a schema from text. The grammar is:
+//
+//	schema = alt { "|" alt }
+//	alt    = "any"
+//	       | "int" [ range ]
+//	       | "string" { "len" range | "%" number }
+//	       | "list" "[" schema "]" { "len" range | "%" number }
+//	       | "dict" "{" { field [ "," ] } "}" [ "len" range ]
+//	       | "dict" "[" schema "]" [ "len" range ]
+//	field  = ( key [ "?" ] | "*" ) ":" schema
+//	key    = identifier | quoted string
+//	range  = number | number ".." [ number ] | ".." number
+//
+// A "?" marks an optional key, "*" gives the schema of the keys which are
+// not listed. "dict [s]" is a dict with arbitrary keys whose values match s.
+// Identifiers consist of letters, digits and the characters "_-."; other
+// keys are written as Go quoted strings. A "#" starts a comment which runs
+// to the end of the line. For example:
+//
+//	dict {
+//		announce?: string
+//		info: dict {
+//			name: string len 1..
+//			"piece length": int 16384..
+//			pieces: string %20
+//			length?: int 0..
+//			files?: list [dict { length: int 0.., path: list [string] len 1.. }]
+//		}
+//		*: any # other keys are allowed
+//	}
+func Parse(text string) (*Schema, error) {
+	p := &parser{text: text}
+	p.next()
+	s := p.schema()
+	if p.err == nil && p.tok != "" {
+		p.fail("unexpected %q", p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics on errors. It is meant for schemas
+// defined in the source code.
+func MustParse(text string) *Schema {
+	s, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parser is a recursive descent parser. After an error all methods return
+// immediately with zero values.
+type parser struct {
+	text string
+	pos  int // position after tok
+	tok  string
+	line int
+	err  error
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("schema: line %d: %s", p.line+1, fmt.Sprintf(format, args...))
+	}
+}
+
+func isIdent(r byte) bool {
+	return r == '_' || r == '-' || r == '.' || r < unicode.MaxASCII && (unicode.IsLetter(rune(r)) || unicode.IsDigit(rune(r)))
+}
+
+// next reads the next token into tok. tok is empty at the end of the text.
+func (p *parser) next() {
+	// skip spaces and comments
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		if c == '#' {
+			for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			break
+		}
+		if c == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+	if p.pos == len(p.text) {
+		p.tok = ""
+		return
+	}
+
+	start := p.pos
+	c := p.text[p.pos]
+	switch {
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.text) && p.text[p.pos] != '"' && p.text[p.pos] != '\n' {
+			if p.text[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		p.pos++
+	case strings.HasPrefix(p.text[p.pos:], ".."):
+		p.pos += 2
+	case c == '-' || '0' <= c && c <= '9':
+		p.pos++
+		for p.pos < len(p.text) && '0' <= p.text[p.pos] && p.text[p.pos] <= '9' {
+			p.pos++
+		}
+	case isIdent(c):
+		for p.pos < len(p.text) && isIdent(p.text[p.pos]) && !strings.HasPrefix(p.text[p.pos:], "..") {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	if p.pos > len(p.text) {
+		p.pos = len(p.text)
+	}
+	p.tok = p.text[start:p.pos]
+}
+
+func (p *parser) expect(tok string) {
+	if p.err != nil {
+		return
+	}
+	if p.tok != tok {
+		p.fail("want %q, got %q", tok, p.tok)
+		return
+	}
+	p.next()
+}
+
+func (p *parser) schema() *Schema {
+	s := p.alt()
+	if p.tok != "|" {
+		return s
+	}
+	alts := []*Schema{s}
+	for p.err == nil && p.tok == "|" {
+		p.next()
+		alts = append(alts, p.alt())
+	}
+	return OneOf(alts...)
+}
+
+func (p *parser) alt() *Schema {
+	if p.err != nil {
+		return nil
+	}
+	tok := p.tok
+	p.next()
+	switch tok {
+	case "any":
+		return Any()
+	case "int":
+		s := Int()
+		if p.tok == ".." || p.isNumber() {
+			s.min, s.max = p.rangeBounds()
+		}
+		return s
+	case "string":
+		return p.lengths(String())
+	case "list":
+		p.expect("[")
+		elem := p.schema()
+		p.expect("]")
+		return p.lengths(List(elem))
+	case "dict":
+		switch p.tok {
+		case "[":
+			p.next()
+			elem := p.schema()
+			p.expect("]")
+			return p.lengths(Map(elem))
+		case "{":
+			p.next()
+			return p.lengths(p.dict())
+		}
+		p.fail("want \"{\" or \"[\" after dict, got %q", p.tok)
+		return nil
+	}
+	p.fail("unexpected %q", tok)
+	return nil
+}
+
+func (p *parser) dict() *Schema {
+	s := Dict()
+	seen := make(map[string]bool)
+	for p.err == nil && p.tok != "}" {
+		if p.tok == "" {
+			p.fail("unterminated dict")
+			return nil
+		}
+		var f Field
+		star := p.tok == "*"
+		if star {
+			p.next()
+		} else {
+			f.Key = p.key()
+			if p.tok == "?" {
+				f.Optional = true
+				p.next()
+			}
+		}
+		p.expect(":")
+		f.Schema = p.schema()
+		switch {
+		case star && s.extra != nil:
+			p.fail("duplicate \"*\"")
+		case star:
+			s.extra = f.Schema
+		case seen[f.Key]:
+			p.fail("duplicate key %q", f.Key)
+		default:
+			seen[f.Key] = true
+			s.fields = append(s.fields, f)
+		}
+		if p.tok == "," {
+			p.next()
+		}
+	}
+	p.expect("}")
+	return s
+}
+
+func (p *parser) key() string {
+	tok := p.tok
+	p.next()
+	if strings.HasPrefix(tok, `"`) {
+		key, err := strconv.Unquote(tok)
+		if err != nil {
+			p.fail("invalid key %s", tok)
+		}
+		return key
+	}
+	if tok == "" || !isIdent(tok[0]) {
+		p.fail("want a key, got %q", tok)
+	}
+	return tok
+}
+
+// lengths parses the length constraints which follow a string, list or
+// dict.
+func (p *parser) lengths(s *Schema) *Schema {
+	for p.err == nil {
+		switch {
+		case p.tok == "len":
+			p.next()
+			s.min, s.max = p.rangeBounds()
+		case p.tok == "%" && s.kind != kindDict:
+			p.next()
+			n := p.number()
+			if n <= 0 {
+				p.fail("invalid multiple %d", n)
+			}
+			s.multiple = n
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+func (p *parser) isNumber() bool {
+	return p.tok != "" && (p.tok[0] == '-' || '0' <= p.tok[0] && p.tok[0] <= '9')
+}
+
+func (p *parser) number() int64 {
+	tok := p.tok
+	p.next()
+	n, err := strconv.ParseInt(tok, 10, 64)
+	if err != nil {
+		p.fail("want a number, got %q", tok)
+	}
+	return n
+}
+
+// rangeBounds parses a range. A single number is both bounds.
+func (p *parser) rangeBounds() (min, max *int64) {
+	if p.tok != ".." {
+		n := p.number()
+		min = &n
+		if p.tok != ".." {
+			return min, min
+		}
+	}
+	p.next() // ".."
+	if p.isNumber() {
+		n := p.number()
+		max = &n
+	}
+	if min == nil && max == nil {
+		p.fail("empty range")
+	}
+	return min, max
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func int64p(n int64) *int64 { return &n }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Schema
+	}{
+		{"Any", "any", Any()},
+		{"Int", "int", Int()},
+		{"Int/Range", "int -1..10", Int().Min(-1).Max(10)},
+		{"Int/Min", "int 0..", Int().Min(0)},
+		{"Int/Max", "int ..5", Int().Max(5)},
+		{"Int/Exact", "int 2", Int().Min(2).Max(2)},
+		{"String", "string len 1..255 %5", String().Min(1).Max(255).MultipleOf(5)},
+		{"List", "list [string] len 1..", List(String()).Min(1)},
+		{"Map", "dict [int]", Map(Int())},
+		{"Union", "list[string] | string", OneOf(List(String()), String())},
+		{"Dict", `dict { a: int, "piece length"?: string   *: any }`, Dict(Required("a", Int()), Optional("piece length", String())).Extra(Any())},
+		{"Dict/Nested", "dict {\n  # comment\n  added.f: dict { x-y: list [int] }\n}", Dict(Required("added.f", Dict(Required("x-y", List(Int())))))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.input)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Empty", "", `schema: line 1: unexpected ""`},
+		{"Unknown", "float", `schema: line 1: unexpected "float"`},
+		{"Trailing", "int int", `schema: line 1: unexpected "int"`},
+		{"Empty range", "int ..", "schema: line 1: empty range"},
+		{"Unterminated", "dict {\na: int\n", "schema: line 3: unterminated dict"},
+		{"Duplicate", "dict { a: int, a: int }", `schema: line 1: duplicate key "a"`},
+		{"Missing colon", "dict { a int }", `schema: line 1: want ":", got "int"`},
+		{"Bad multiple", "string %0", "schema: line 1: invalid multiple 0"},
+		{"List bracket", "list string", `schema: line 1: want "[", got "string"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.input)
+			if err == nil || err.Error() != test.want {
+				t.Error("got:", err, "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestParseDocExample(t *testing.T) {
+	s := MustParse(`
+	dict {
+		announce?: string
+		info: dict {
+			name: string len 1..
+			"piece length": int 16384..
+			pieces: string %20
+			length?: int 0..
+			files?: list [dict { length: int 0.., path: list [string] len 1.. }]
+		}
+		*: any # other keys are allowed
+	}`)
+	if err := s.Validate(parse(t, "d7:comment1:c4:infod5:filesld6:lengthi1e4:pathl1:aeee4:name1:n12:piece lengthi16384e6:pieces0:ee")); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
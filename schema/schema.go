@@ -0,0 +1,303 @@
+// Package schema describes the expected shape of bencoded documents and
+// validates Value trees against it. A schema is built in Go with the
+// functions of this package or parsed from text with Parse.
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ortymid/bencode"
+)
+
+type kind int
+
+const (
+	kindAny kind = iota
+	kindInt
+	kindString
+	kindList
+	kindDict
+	kindUnion
+)
+
+var kindNames = [...]string{"any", "int", "string", "list", "dict", "union"}
+
+// Schema describes a value. Schemas are immutable once built; the methods
+// which add constraints return a modified copy. A nil *Schema, such as in
+// List(nil) or Required(key, nil), accepts every value like Any.
+type Schema struct {
+	kind kind
+	// min and max bound the value of an int or the length of a string,
+	// list or dict.
+	min, max *int64
+	// multiple, if not zero, must divide the length of a string or list.
+	multiple int64
+	// elem is the schema of list items, or of the values of a dict with
+	// arbitrary keys.
+	elem   *Schema
+	fields []Field
+	// extra is the schema of dict values whose keys are not among fields.
+	// Such keys are violations if it is nil.
+	extra *Schema
+	alts  []*Schema
+}
+
+// Field is a dict key with the schema of its value.
+type Field struct {
+	Key      string
+	Schema   *Schema
+	Optional bool
+}
+
+// Required returns a field which must be present.
+func Required(key string, s *Schema) Field {
+	return Field{Key: key, Schema: s}
+}
+
+// Optional returns a field which may be absent.
+func Optional(key string, s *Schema) Field {
+	return Field{Key: key, Schema: s, Optional: true}
+}
+
+// Any returns a schema which accepts every value.
+func Any() *Schema { return &Schema{kind: kindAny} }
+
+// Int returns a schema of an integer.
+func Int() *Schema { return &Schema{kind: kindInt} }
+
+// String returns a schema of a string.
+func String() *Schema { return &Schema{kind: kindString} }
+
+// List returns a schema of a list whose items match elem.
+func List(elem *Schema) *Schema { return &Schema{kind: kindList, elem: elem} }
+
+// Dict returns a schema of a dict with the given fields. Keys which are
+// not among the fields are violations unless allowed with Extra.
+func Dict(fields ...Field) *Schema {
+	return &Schema{kind: kindDict, fields: fields}
+}
+
+// Map returns a schema of a dict with arbitrary keys whose values match
+// elem.
+func Map(elem *Schema) *Schema {
+	if elem == nil {
+		elem = Any()
+	}
+	return &Schema{kind: kindDict, extra: elem}
+}
+
+// OneOf returns a schema of a value which matches at least one of alts.
+func OneOf(alts ...*Schema) *Schema { return &Schema{kind: kindUnion, alts: alts} }
+
+func (s *Schema) clone() *Schema {
+	c := *s
+	return &c
+}
+
+// Min returns s with the minimum of an integer, or the minimum length of
+// a string, list or dict, set to n.
+func (s *Schema) Min(n int64) *Schema {
+	c := s.clone()
+	c.min = &n
+	return c
+}
+
+// Max returns s with the maximum of an integer, or the maximum length of
+// a string, list or dict, set to n.
+func (s *Schema) Max(n int64) *Schema {
+	c := s.clone()
+	c.max = &n
+	return c
+}
+
+// MultipleOf returns s with the length of a string or list required to be
+// a multiple of n.
+func (s *Schema) MultipleOf(n int64) *Schema {
+	c := s.clone()
+	c.multiple = n
+	return c
+}
+
+// Extra returns a dict schema which allows keys other than its fields,
+// with values matching extra.
+func (s *Schema) Extra(extra *Schema) *Schema {
+	c := s.clone()
+	c.extra = extra
+	return c
+}
+
+// Violation is a mismatch between a value and a schema. Path is the slash
+// separated path of the value, such as /info/files/0/length.
+type Violation struct {
+	Path string
+	Msg  string
+}
+
+func (v Violation) Error() string { return v.Path + ": " + v.Msg }
+
+// Violations is the error returned by Validate.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	msgs := make([]string, len(v))
+	for i, violation := range v {
+		msgs[i] = violation.Error()
+	}
+	return "schema: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks v against s and returns every violation as Violations,
+// or nil if v matches.
+func (s *Schema) Validate(v bencode.Value) error {
+	var violations Violations
+	s.validate(v, "", &violations)
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+func joinPath(path, elem string) string {
+	return path + "/" + elem
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func valueKind(v bencode.Value) kind {
+	switch v.(type) {
+	case bencode.Int:
+		return kindInt
+	case bencode.String:
+		return kindString
+	case bencode.List:
+		return kindList
+	case *bencode.Dict:
+		return kindDict
+	}
+	return kindAny
+}
+
+func (s *Schema) validate(v bencode.Value, path string, violations *Violations) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{rootPath(path), fmt.Sprintf(format, args...)})
+	}
+
+	if s == nil || s.kind == kindAny {
+		return
+	}
+	if s.kind == kindUnion {
+		s.validateUnion(v, path, violations)
+		return
+	}
+	if k := valueKind(v); k != s.kind {
+		report("want %s, got %s", kindNames[s.kind], kindNames[k])
+		return
+	}
+
+	switch v := v.(type) {
+	case bencode.Int:
+		if s.min != nil && int64(v) < *s.min {
+			report("%d is less than %d", v, *s.min)
+		}
+		if s.max != nil && int64(v) > *s.max {
+			report("%d is greater than %d", v, *s.max)
+		}
+	case bencode.String:
+		s.validateLen(int64(len(v)), report)
+	case bencode.List:
+		s.validateLen(int64(len(v)), report)
+		for i, item := range v {
+			s.elem.validate(item, joinPath(path, strconv.Itoa(i)), violations)
+		}
+	case *bencode.Dict:
+		s.validateDict(v, path, report, violations)
+	}
+}
+
+func (s *Schema) validateLen(n int64, report func(string, ...interface{})) {
+	if s.min != nil && n < *s.min {
+		report("length %d is less than %d", n, *s.min)
+	}
+	if s.max != nil && n > *s.max {
+		report("length %d is greater than %d", n, *s.max)
+	}
+	if s.multiple != 0 && n%s.multiple != 0 {
+		report("length %d is not a multiple of %d", n, s.multiple)
+	}
+}
+
+func (s *Schema) validateDict(d *bencode.Dict, path string, report func(string, ...interface{}), violations *Violations) {
+	keys := d.Keys()
+	s.validateLen(int64(len(keys)), report)
+
+	known := make(map[string]bool, len(s.fields))
+	for _, f := range s.fields {
+		known[f.Key] = true
+		v := d.Get(bencode.String(f.Key))
+		if v == nil {
+			if !f.Optional {
+				report("missing key %q", f.Key)
+			}
+			continue
+		}
+		f.Schema.validate(v, joinPath(path, f.Key), violations)
+	}
+
+	var unknown []string
+	for _, key := range keys {
+		if known[string(key)] {
+			continue
+		}
+		if s.extra != nil {
+			s.extra.validate(d.Get(key), joinPath(path, string(key)), violations)
+		} else {
+			unknown = append(unknown, string(key))
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		report("unexpected key %q", key)
+	}
+}
+
+// validateUnion reports the violations of the only alternative of the kind
+// of v, or a kind mismatch if there is no such alternative.
+func (s *Schema) validateUnion(v bencode.Value, path string, violations *Violations) {
+	var (
+		candidates []*Schema
+		want       []string
+	)
+	for _, alt := range s.alts {
+		if alt == nil {
+			return
+		}
+		if alt.kind == kindAny || alt.kind == kindUnion || alt.kind == valueKind(v) {
+			var altViolations Violations
+			alt.validate(v, path, &altViolations)
+			if len(altViolations) == 0 {
+				return
+			}
+			candidates = append(candidates, alt)
+		}
+		want = append(want, kindNames[alt.kind])
+	}
+	switch len(candidates) {
+	case 0:
+	case 1:
+		candidates[0].validate(v, path, violations)
+		return
+	default:
+		*violations = append(*violations, Violation{rootPath(path), "matches none of the alternatives"})
+		return
+	}
+	*violations = append(*violations, Violation{rootPath(path),
+		fmt.Sprintf("want %s, got %s", strings.Join(want, " or "), kindNames[valueKind(v)])})
+}
@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ortymid/bencode"
+)
+
+func parse(t *testing.T, s string) bencode.Value {
+	v, err := bencode.NewParser(bytes.NewReader([]byte(s))).Parse()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	torrent := Dict(
+		Optional("announce", String()),
+		Required("info", Dict(
+			Required("name", String().Min(1)),
+			Required("piece length", Int().Min(1)),
+			Required("pieces", String().MultipleOf(20)),
+			Optional("private", Int().Min(0).Max(1)),
+			Optional("length", Int().Min(0)),
+			Optional("files", List(Dict(
+				Required("length", Int().Min(0)),
+				Required("path", List(String()).Min(1)),
+			)).Min(1)),
+		)),
+	).Extra(Any())
+
+	tests := []struct {
+		name  string
+		input string
+		want  Violations
+	}{
+		{"Valid", "d8:announce1:a7:comment1:c4:infod6:lengthi1e4:name1:n12:piece lengthi1e6:pieces0:ee", nil},
+		{"Missing key", "d4:infod4:name1:n6:pieces0:ee", Violations{{"/info", `missing key "piece length"`}}},
+		{"Wrong kind", "d8:announcei1e4:infoi1ee", Violations{{"/announce", "want string, got int"}, {"/info", "want dict, got int"}}},
+		{"Unexpected key", "d4:infod4:name1:n12:piece lengthi1e6:pieces0:1:xi1eee", Violations{{"/info", `unexpected key "x"`}}},
+		{"Constraints", "d4:infod4:name0:12:piece lengthi0e6:pieces1:x7:privatei2eee", Violations{
+			{"/info/name", "length 0 is less than 1"},
+			{"/info/piece length", "0 is less than 1"},
+			{"/info/pieces", "length 1 is not a multiple of 20"},
+			{"/info/private", "2 is greater than 1"},
+		}},
+		{"Nested list", "d4:infod5:filesld6:lengthi1e4:pathleed6:lengthi-1e4:pathl1:aeee4:name1:n12:piece lengthi1e6:pieces0:ee", Violations{
+			{"/info/files/0/path", "length 0 is less than 1"},
+			{"/info/files/1/length", "-1 is less than 0"},
+		}},
+		{"Root", "i1e", Violations{{"/", "want dict, got int"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := torrent.Validate(parse(t, test.input))
+			if test.want == nil {
+				if err != nil {
+					t.Error("unexpected error:", err)
+				}
+				return
+			}
+			var got Violations
+			if !errors.As(err, &got) {
+				t.Fatal("got:", err, "want:", test.want)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %v \nwant: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateUnion(t *testing.T) {
+	// a list of strings or a single string, as in url-list (BEP 19)
+	urlList := OneOf(List(String().Min(1)), String().Min(1))
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		input  string
+		want   Violations
+	}{
+		{"String", urlList, "3:url", nil},
+		{"List", urlList, "l3:urle", nil},
+		{"Kind", urlList, "i1e", Violations{{"/", "want list or string, got int"}}},
+		{"Alternative", urlList, "l0:e", Violations{{"/0", "length 0 is less than 1"}}},
+		{"Several alternatives", OneOf(Int().Max(1), Int().Min(10)), "i5e", Violations{{"/", "matches none of the alternatives"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.schema.Validate(parse(t, test.input))
+			if test.want == nil {
+				if err != nil {
+					t.Error("unexpected error:", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, test.want) {
+				t.Errorf("\ngot: %v \nwant: %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	s := Map(Int()).Max(2)
+	err := s.Validate(parse(t, "d1:ai1e1:b0:1:ci3ee"))
+	want := Violations{{"/", "length 3 is greater than 2"}, {"/b", "want int, got string"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("\ngot: %v \nwant: %v", err, want)
+	}
+}
+
+func TestNilSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		input  string
+	}{
+		{"Nil", nil, "i1e"},
+		{"List", List(nil), "li1e0:e"},
+		{"Map", Map(nil), "d1:ai1e1:blee"},
+		{"Fields", Dict(Required("a", nil), Optional("b", nil)), "d1:ali1ee1:bdee"},
+		{"OneOf", OneOf(nil, Int()), "0:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.schema.Validate(parse(t, test.input)); err != nil {
+				t.Error("unexpected error:", err)
+			}
+		})
+	}
+}
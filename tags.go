@@ -0,0 +1,68 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// tagOptions is the string following a comma in a struct field's "bencode"
+// tag, or the empty string.
+type tagOptions string
+
+// parseTag splits a struct field's bencode tag into its name and options.
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+// Contains reports whether a comma-separated list of options contains
+// a particular option.
+func (o tagOptions) Contains(option string) bool {
+	s := string(o)
+	for s != "" {
+		var name string
+		if i := strings.Index(s, ","); i >= 0 {
+			name, s = s[:i], s[i+1:]
+		} else {
+			name, s = s, ""
+		}
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKey returns the dict key of a struct field and its tag options. The
+// key is the tag name, or the field name if the tag has no name. A field
+// tagged with "-" is skipped, which is reported by ok.
+func fieldKey(field reflect.StructField) (key string, opts tagOptions, ok bool) {
+	tag := field.Tag.Get("bencode")
+	if tag == "-" {
+		return "", "", false
+	}
+	key, opts = parseTag(tag)
+	if key == "" {
+		key = field.Name
+	}
+	return key, opts, true
+}
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// checkRest returns an error if the field tagged with the "rest" option
+// cannot hold the unmatched keys of a dict, or if the struct already has
+// such a field.
+func checkRest(field reflect.StructField, dup bool) error {
+	if dup {
+		return fmt.Errorf("bencode: struct has more than one rest field")
+	}
+	t := field.Type
+	if t == dictPtrType || t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem() == rawMessageType {
+		return nil
+	}
+	return fmt.Errorf("bencode: rest field %s must be a map[string]RawMessage or *Dict, not %s", field.Name, t)
+}
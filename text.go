@@ -0,0 +1,48 @@
+package bencode
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isTextMarshaler reports whether values of type t are encoded as strings
+// by encoding.BinaryMarshaler or encoding.TextMarshaler.
+func isTextMarshaler(t reflect.Type) bool {
+	return t.Implements(binaryMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// isTextUnmarshaler reports whether values of type t are decoded from
+// strings by encoding.BinaryUnmarshaler or encoding.TextUnmarshaler.
+func isTextUnmarshaler(t reflect.Type) bool {
+	return t.Implements(binaryUnmarshalerType) || t.Implements(textUnmarshalerType)
+}
+
+// marshalText returns the string form of v, whose type satisfies
+// isTextMarshaler. MarshalBinary is preferred, as bencoded strings are
+// binary.
+func marshalText(v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, fmt.Errorf("bencode: cannot encode nil %s", v.Type())
+	}
+	if m, ok := v.Interface().(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return v.Interface().(encoding.TextMarshaler).MarshalText()
+}
+
+// unmarshalText decodes the string b into the value pointed by p, whose
+// type satisfies isTextUnmarshaler. UnmarshalBinary is preferred.
+func unmarshalText(p reflect.Value, b []byte) error {
+	if u, ok := p.Interface().(encoding.BinaryUnmarshaler); ok {
+		return u.UnmarshalBinary(b)
+	}
+	return p.Interface().(encoding.TextUnmarshaler).UnmarshalText(b)
+}
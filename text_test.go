@@ -0,0 +1,84 @@
+package bencode
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// hexID is encoded as text only.
+type hexID [2]byte
+
+func (id hexID) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(id[:])), nil
+}
+
+func (id *hexID) UnmarshalText(b []byte) error {
+	_, err := hex.Decode(id[:], b)
+	return err
+}
+
+func TestTextMarshaler(t *testing.T) {
+	type peers struct {
+		Self  netip.AddrPort            `bencode:"self"`
+		Known map[netip.AddrPort]string `bencode:"known"`
+		IDs   map[hexID]hexID           `bencode:"ids"`
+		Owner *hexID                    `bencode:"owner"`
+	}
+	a := netip.MustParseAddrPort("1.2.3.4:6881")
+	b := netip.MustParseAddrPort("[::1]:80")
+	input := peers{
+		Self:  a,
+		Known: map[netip.AddrPort]string{a: "a", b: "b"},
+		IDs:   map[hexID]hexID{{0xab, 0xcd}: {0x01, 0x02}, {0x00, 0xff}: {}},
+		Owner: &hexID{0xff, 0xff},
+	}
+
+	data, err := Marshal(input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// netip.AddrPort is encoded in its binary form rather than as text
+	binA, _ := a.MarshalBinary()
+	binB, _ := b.MarshalBinary()
+	want := "d3:idsd4:00ff4:00004:abcd4:0102e5:knownd18:" + string(binB) + "1:b6:" + string(binA) + "1:ae" +
+		"5:owner4:ffff4:self6:" + string(binA) + "e"
+	if string(data) != want {
+		t.Errorf("\ngot: %q \nwant: %q", data, want)
+	}
+
+	var got peers
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("\ngot: %v \nwant: %v", got, input)
+	}
+}
+
+func TestTextMarshalerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		dst   interface{}
+	}{
+		{"Not a string", "i1e", new(hexID)},
+		{"Invalid text", "4:zzzz", new(hexID)},
+		{"Invalid key", "d4:zzzzi1ee", new(map[hexID]int)},
+		{"Invalid binary", "3:abc", new(netip.Addr)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Unmarshal([]byte(test.input), test.dst); err == nil {
+				t.Error("no error for", test.input)
+			}
+		})
+	}
+
+	if _, err := Marshal(map[*hexID]int{nil: 1}); err == nil {
+		t.Error("no error for a nil key")
+	}
+}
@@ -0,0 +1,104 @@
+package bencode
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// The range of times which can be encoded, the years 1 to 9999, in Unix
+// seconds.
+const (
+	minUnix = -62135596800
+	maxUnix = 253402300799
+)
+
+// timeUnit returns the unit selected by the tag options of a time.Time or
+// time.Duration field, or of a pointer to one: "unixsec" and "unixms" for
+// times, "seconds" and "ms" for durations. It returns 0 if there is none.
+func timeUnit(field reflect.StructField, opts tagOptions) (time.Duration, error) {
+	var unit time.Duration
+	var want reflect.Type
+	switch {
+	case opts.Contains("unixsec"):
+		unit, want = time.Second, timeType
+	case opts.Contains("unixms"):
+		unit, want = time.Millisecond, timeType
+	case opts.Contains("seconds"):
+		unit, want = time.Second, durationType
+	case opts.Contains("ms"):
+		unit, want = time.Millisecond, durationType
+	default:
+		return 0, nil
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != want {
+		return 0, fmt.Errorf("bencode: time unit option on field %s of type %s", field.Name, field.Type)
+	}
+	return unit, nil
+}
+
+// encodeTime returns t in Unix seconds, or in Unix milliseconds if unit is
+// time.Millisecond.
+func encodeTime(t time.Time, unit time.Duration) (Int, error) {
+	if sec := t.Unix(); sec < minUnix || sec > maxUnix {
+		return 0, fmt.Errorf("bencode: time %v out of range", t)
+	}
+	if unit == time.Millisecond {
+		return Int(t.UnixMilli()), nil
+	}
+	return Int(t.Unix()), nil
+}
+
+// decodeTime returns the UTC time of n Unix seconds, or of n Unix
+// milliseconds if unit is time.Millisecond.
+func decodeTime(n int64, unit time.Duration) (time.Time, error) {
+	if unit == time.Millisecond {
+		if n < minUnix*1000 || n > maxUnix*1000+999 {
+			return time.Time{}, fmt.Errorf("bencode: %dms out of time range", n)
+		}
+		return time.UnixMilli(n).UTC(), nil
+	}
+	if n < minUnix || n > maxUnix {
+		return time.Time{}, fmt.Errorf("bencode: %ds out of time range", n)
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// encodeDuration returns d in the unit, truncated. A unit of 0 stands for
+// nanoseconds.
+func encodeDuration(d, unit time.Duration) Int {
+	if unit == 0 {
+		return Int(d)
+	}
+	return Int(d / unit)
+}
+
+// decodeDuration returns the duration of n units. A unit of 0 stands for
+// nanoseconds.
+func decodeDuration(n int64, unit time.Duration) (time.Duration, error) {
+	if unit == 0 {
+		return time.Duration(n), nil
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, fmt.Errorf("bencode: %d%s overflows time.Duration", n, unitSymbol(unit))
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func unitSymbol(unit time.Duration) string {
+	if unit == time.Millisecond {
+		return "ms"
+	}
+	return "s"
+}
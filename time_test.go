@@ -0,0 +1,87 @@
+package bencode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	type announce struct {
+		Created  time.Time      `bencode:"created"`
+		Updated  *time.Time     `bencode:"updated,unixms"`
+		Seen     time.Time      `bencode:"seen,omitempty"`
+		Interval time.Duration  `bencode:"interval,seconds"`
+		Min      *time.Duration `bencode:"min,ms"`
+		Timeout  time.Duration  `bencode:"timeout"`
+	}
+	updated := time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	min := 1500 * time.Millisecond
+	input := announce{
+		Created:  time.Unix(1600000000, 0).UTC(),
+		Updated:  &updated,
+		Interval: 30 * time.Minute,
+		Min:      &min,
+		Timeout:  time.Second,
+	}
+	want := "d7:createdi1600000000e8:intervali1800e3:mini1500e7:timeouti1000000000e7:updatedi1577934245006ee"
+
+	data, err := Marshal(input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(data) != want {
+		t.Errorf("\ngot: %q \nwant: %q", data, want)
+	}
+
+	var got announce
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !got.Created.Equal(input.Created) || !got.Updated.Equal(updated) || !got.Seen.IsZero() ||
+		got.Interval != input.Interval || *got.Min != min || got.Timeout != input.Timeout {
+		t.Errorf("\ngot: %+v \nwant: %+v", got, input)
+	}
+
+	// without a tag, a time is decoded from Unix seconds
+	var created time.Time
+	if err := Unmarshal([]byte("i1600000000e"), &created); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !created.Equal(input.Created) {
+		t.Error("got:", created, "want:", input.Created)
+	}
+}
+
+func TestTimeErrors(t *testing.T) {
+	type times struct {
+		Sec  time.Time     `bencode:"sec,unixsec"`
+		Ms   time.Time     `bencode:"ms,unixms"`
+		Secs time.Duration `bencode:"secs,seconds"`
+	}
+	decodeTests := []string{
+		"d3:seci253402300800ee",
+		"d3:seci-62135596801ee",
+		"d2:msi253402300800000ee",
+		"d4:secsi9223372036854775807ee",
+		"d3:sec4:soonee",
+	}
+	for _, input := range decodeTests {
+		var got times
+		if err := Unmarshal([]byte(input), &got); err == nil {
+			t.Error("no error for", input)
+		}
+	}
+
+	var wrongType struct {
+		N int64 `bencode:"n,unixsec"`
+	}
+	if err := Unmarshal([]byte("d1:ni1ee"), &wrongType); err == nil {
+		t.Error("no error for", "unixsec on int64")
+	}
+	if _, err := Marshal(wrongType); err == nil {
+		t.Error("no error for", "unixsec on int64")
+	}
+	if _, err := Marshal(times{Sec: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}); err == nil {
+		t.Error("no error for", "year 10000")
+	}
+}
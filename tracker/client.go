@@ -0,0 +1,183 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// Announce events.
+const (
+	EventNone      = ""
+	EventStarted   = "started"
+	EventCompleted = "completed"
+	EventStopped   = "stopped"
+)
+
+// AnnounceRequest holds the parameters of an announce request.
+type AnnounceRequest struct {
+	InfoHash   metainfo.HashV1
+	PeerID     [20]byte
+	Port       int
+	Uploaded   int64
+	Downloaded int64
+	Left       int64
+	Event      string
+	// Compact asks for peers in the compact form (BEP 23).
+	Compact bool
+	// NumWant is the number of peers wanted; zero leaves it to the tracker.
+	NumWant   int
+	Key       string
+	TrackerID string
+}
+
+// query returns the request as URL query parameters. Binary values are
+// escaped byte by byte.
+func (r *AnnounceRequest) query() string {
+	params := []string{
+		"info_hash=" + escape(string(r.InfoHash[:])),
+		"peer_id=" + escape(string(r.PeerID[:])),
+		"port=" + strconv.Itoa(r.Port),
+		"uploaded=" + strconv.FormatInt(r.Uploaded, 10),
+		"downloaded=" + strconv.FormatInt(r.Downloaded, 10),
+		"left=" + strconv.FormatInt(r.Left, 10),
+	}
+	if r.Compact {
+		params = append(params, "compact=1")
+	}
+	if r.Event != EventNone {
+		params = append(params, "event="+escape(r.Event))
+	}
+	if r.NumWant != 0 {
+		params = append(params, "numwant="+strconv.Itoa(r.NumWant))
+	}
+	if r.Key != "" {
+		params = append(params, "key="+escape(r.Key))
+	}
+	if r.TrackerID != "" {
+		params = append(params, "trackerid="+escape(r.TrackerID))
+	}
+	return strings.Join(params, "&")
+}
+
+// escape percent-encodes every byte of s except the unreserved characters
+// of RFC 3986. Unlike url.QueryEscape it never turns a space into '+',
+// which some trackers do not understand in binary values.
+func escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+// FailureError is returned when a tracker responds with a failure reason.
+type FailureError struct {
+	Reason string
+}
+
+func (e *FailureError) Error() string { return "tracker: failure: " + e.Reason }
+
+// Client issues requests to HTTP trackers.
+type Client struct {
+	// HTTPClient is used to make requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Announce sends an announce request to the tracker at announceURL. A
+// failure reason in the response is returned as *FailureError.
+func (c *Client) Announce(ctx context.Context, announceURL string, req AnnounceRequest) (*AnnounceResponse, error) {
+	resp := &AnnounceResponse{}
+	if err := c.get(ctx, withQuery(announceURL, req.query()), resp); err != nil {
+		return nil, err
+	}
+	if resp.FailureReason != "" {
+		return nil, &FailureError{resp.FailureReason}
+	}
+	return resp, nil
+}
+
+// Scrape sends a scrape request for the given info-hashes to the tracker
+// at announceURL. The scrape URL is derived as ScrapeURL does.
+func (c *Client) Scrape(ctx context.Context, announceURL string, infoHashes ...metainfo.HashV1) (*ScrapeResponse, error) {
+	scrapeURL, err := ScrapeURL(announceURL)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]string, len(infoHashes))
+	for i, h := range infoHashes {
+		params[i] = "info_hash=" + escape(string(h[:]))
+	}
+
+	resp := &ScrapeResponse{}
+	if err := c.get(ctx, withQuery(scrapeURL, strings.Join(params, "&")), resp); err != nil {
+		return nil, err
+	}
+	if resp.FailureReason != "" {
+		return nil, &FailureError{resp.FailureReason}
+	}
+	return resp, nil
+}
+
+// ScrapeURL derives the scrape URL from an announce URL by replacing the
+// "announce" at the start of the last path segment with "scrape", as BEP 48
+// describes. It fails for trackers which do not support scraping.
+func ScrapeURL(announceURL string) (string, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return "", err
+	}
+	i := strings.LastIndex(u.Path, "/")
+	if !strings.HasPrefix(u.Path[i+1:], "announce") {
+		return "", errors.New("tracker: scrape is not supported by " + announceURL)
+	}
+	u.Path = u.Path[:i+1] + "scrape" + u.Path[i+1+len("announce"):]
+	return u.String(), nil
+}
+
+func withQuery(u, query string) string {
+	if strings.Contains(u, "?") {
+		return u + "&" + query
+	}
+	return u + "?" + query
+}
+
+func (c *Client) get(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("tracker: unexpected status %s", resp.Status)
+	}
+	return bencode.NewDecoder(resp.Body).Decode(v)
+}
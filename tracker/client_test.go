@@ -0,0 +1,149 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ortymid/bencode/metainfo"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Unreserved", "AZaz09-._~", "AZaz09-._~"},
+		{"Space", " ", "%20"},
+		{"Binary", "\x00\x12\xab\xff", "%00%12%AB%FF"},
+		{"Reserved", "&=+%", "%26%3D%2B%25"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := escape(test.input); got != test.want {
+				t.Error("got:", got, "want:", test.want)
+			}
+		})
+	}
+}
+
+func TestScrapeURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Plain", "http://example.com/announce", "http://example.com/scrape"},
+		{"Suffix", "http://example.com/x/announce.php", "http://example.com/x/scrape.php"},
+		{"Query", "http://example.com/announce?passkey=abc", "http://example.com/scrape?passkey=abc"},
+		{"Unsupported", "http://example.com/a", ""},
+		{"Not last", "http://example.com/announce/x", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ScrapeURL(test.input)
+			if test.want == "" {
+				if err == nil {
+					t.Error("no error for", test.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got != test.want {
+				t.Error("got:", got, "want:", test.want)
+			}
+		})
+	}
+}
+
+func peerID(s string) (id [20]byte) {
+	copy(id[:], s)
+	return id
+}
+
+func TestClientServer(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+	announceURL := srv.URL + "/announce"
+	ctx := context.Background()
+	c := &Client{HTTPClient: srv.Client()}
+
+	// binary bytes which need escaping, including a space and '+'
+	infoHash := metainfo.HashV1{0x00, ' ', '+', '%', '&', 0xff}
+	seeder := AnnounceRequest{InfoHash: infoHash, PeerID: peerID("-XX0001-seeder\xff\x00  "), Port: 6881, Event: EventStarted}
+	leecher := AnnounceRequest{InfoHash: infoHash, PeerID: peerID("-XX0001-leecher"), Port: 6882, Left: 100, Compact: true, Event: EventStarted}
+
+	if _, err := c.Announce(ctx, announceURL, seeder); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	resp, err := c.Announce(ctx, announceURL, leecher)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want := Peers{{IP: "127.0.0.1", Port: 6881}}
+	if !reflect.DeepEqual(resp.Peers, want) {
+		t.Errorf("\ngot: %v \nwant: %v", resp.Peers, want)
+	}
+	if resp.Complete != 1 || resp.Incomplete != 1 {
+		t.Error("got:", resp.Complete, resp.Incomplete, "want:", 1, 1)
+	}
+
+	// non-compact responses carry the peer id
+	seeder.Event = EventNone
+	resp, err = c.Announce(ctx, announceURL, seeder)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	want = Peers{{ID: string(leecher.PeerID[:]), IP: "127.0.0.1", Port: 6882}}
+	if !reflect.DeepEqual(resp.Peers, want) {
+		t.Errorf("\ngot: %v \nwant: %v", resp.Peers, want)
+	}
+
+	leecher.Event, leecher.Left = EventCompleted, 0
+	if _, err := c.Announce(ctx, announceURL, leecher); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	scrape, err := c.Scrape(ctx, announceURL, infoHash, metainfo.HashV1{1})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	f, ok := scrape.File(infoHash)
+	if wantFile := (ScrapeFile{Complete: 2, Downloaded: 1}); !ok || f != wantFile {
+		t.Error("got:", f, "want:", wantFile)
+	}
+	if _, ok := scrape.File(metainfo.HashV1{1}); ok {
+		t.Error("got: unknown torrent in scrape")
+	}
+
+	seeder.Event = EventStopped
+	if _, err := c.Announce(ctx, announceURL, seeder); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if got := srv.Config.Handler.(*Server).Peers(infoHash); len(got) != 1 || got[0].Port != 6882 {
+		t.Error("got:", got, "want: the leecher only")
+	}
+}
+
+func TestClientFailure(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+	c := &Client{HTTPClient: srv.Client()}
+
+	// the first info_hash parameter wins, so the one of the request is ignored
+	_, err := c.Announce(context.Background(), srv.URL+"/announce?info_hash=short", AnnounceRequest{})
+	var failure *FailureError
+	if !errors.As(err, &failure) {
+		t.Fatal("got:", err, "want: *FailureError")
+	}
+
+	if _, err := c.Announce(context.Background(), srv.URL+"/unknown", AnnounceRequest{}); err == nil {
+		t.Error("no error for unknown path")
+	}
+}
@@ -0,0 +1,188 @@
+// Package tracker provides the HTTP tracker protocol: announce responses
+// (BEP 3, BEP 23, BEP 7), scrape responses (BEP 48), a client and a minimal
+// in-memory server for tests.
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/krpc"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// AnnounceResponse is the response of a tracker to an announce request.
+// A response with FailureReason set carries no other keys.
+type AnnounceResponse struct {
+	FailureReason  string `bencode:"failure reason,omitempty"`
+	WarningMessage string `bencode:"warning message,omitempty"`
+	Interval       int64  `bencode:"interval"`
+	MinInterval    int64  `bencode:"min interval,omitempty"`
+	TrackerID      string `bencode:"tracker id,omitempty"`
+	Complete       int64  `bencode:"complete"`
+	Incomplete     int64  `bencode:"incomplete"`
+	Peers          Peers  `bencode:"peers"`
+	Peers6         Peers6 `bencode:"peers6,omitempty"`
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (r AnnounceResponse) MarshalBencode() ([]byte, error) {
+	if r.FailureReason != "" {
+		return bencode.Marshal(map[string]string{"failure reason": r.FailureReason})
+	}
+	type plain AnnounceResponse // without the MarshalBencode method
+	return bencode.Marshal(plain(r))
+}
+
+// Peer is a peer of a swarm. ID is empty for peers in the compact form.
+type Peer struct {
+	ID string
+	// IP is an IP address or, in the dictionary form, possibly a DNS name.
+	IP   string
+	Port int
+}
+
+// AddrPort returns the address of the peer if IP is an IP address.
+func (p Peer) AddrPort() (netip.AddrPort, error) {
+	addr, err := netip.ParseAddr(p.IP)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(addr, uint16(p.Port)), nil
+}
+
+// peerDict is the dictionary form of a peer.
+type peerDict struct {
+	ID   string `bencode:"peer id,omitempty"`
+	IP   string `bencode:"ip"`
+	Port int    `bencode:"port"`
+}
+
+// Peers is the "peers" key of an announce response. It is either a list of
+// dicts or a string of 6-byte compact IPv4 peers (BEP 23). It is encoded in
+// the compact form unless a peer has an ID or a non-IPv4 address.
+type Peers []Peer
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (p *Peers) UnmarshalBencode(data []byte) error {
+	peers, err := unmarshalPeers(data, 6)
+	*p = peers
+	return err
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (p Peers) MarshalBencode() ([]byte, error) {
+	return marshalPeers(p, false)
+}
+
+// Peers6 is the "peers6" key of an announce response (BEP 7). It is either
+// a list of dicts or a string of 18-byte compact IPv6 peers.
+type Peers6 []Peer
+
+// UnmarshalBencode implements bencode.Unmarshaler.
+func (p *Peers6) UnmarshalBencode(data []byte) error {
+	peers, err := unmarshalPeers(data, 18)
+	*p = peers
+	return err
+}
+
+// MarshalBencode implements bencode.Marshaler.
+func (p Peers6) MarshalBencode() ([]byte, error) {
+	return marshalPeers(p, true)
+}
+
+// unmarshalPeers decodes either form of a peer list.
+func unmarshalPeers(data []byte, size int) ([]Peer, error) {
+	switch c := bencode.NewScanner(data).Peek(); {
+	case c >= '0' && c <= '9':
+		var compact string
+		if err := bencode.Unmarshal(data, &compact); err != nil {
+			return nil, err
+		}
+		if len(compact)%size != 0 {
+			return nil, fmt.Errorf("tracker: compact peers length %d is not a multiple of %d", len(compact), size)
+		}
+		peers := make([]Peer, 0, len(compact)/size)
+		for i := 0; i < len(compact); i += size {
+			addr, err := krpc.ParseCompactPeer(compact[i : i+size])
+			if err != nil {
+				return nil, err
+			}
+			peers = append(peers, Peer{IP: addr.Addr().String(), Port: int(addr.Port())})
+		}
+		return peers, nil
+	case c == 'l':
+		var dicts []peerDict
+		if err := bencode.Unmarshal(data, &dicts); err != nil {
+			return nil, err
+		}
+		peers := make([]Peer, len(dicts))
+		for i, d := range dicts {
+			peers[i] = Peer{ID: d.ID, IP: d.IP, Port: d.Port}
+		}
+		return peers, nil
+	case c == 'i':
+		return nil, errors.New("tracker: peers must be a list or a string, not an integer")
+	case c == 'd':
+		return nil, errors.New("tracker: peers must be a list or a string, not a dict")
+	}
+	return nil, errors.New("tracker: peers must be a list or a string")
+}
+
+func marshalPeers(peers []Peer, ipv6 bool) ([]byte, error) {
+	compact := make([]byte, 0, len(peers)*18)
+	for _, p := range peers {
+		addr, err := p.AddrPort()
+		if err != nil || p.ID != "" || addr.Addr().Unmap().Is4() == ipv6 {
+			return marshalPeerDicts(peers)
+		}
+		compact = append(compact, krpc.CompactPeer(addr)...)
+	}
+	return bencode.String(compact).Bencode(), nil
+}
+
+func marshalPeerDicts(peers []Peer) ([]byte, error) {
+	dicts := make([]peerDict, len(peers))
+	for i, p := range peers {
+		dicts[i] = peerDict{ID: p.ID, IP: p.IP, Port: p.Port}
+	}
+	return bencode.Marshal(dicts)
+}
+
+// ScrapeResponse is the response of a tracker to a scrape request. Files
+// is keyed by raw 20-byte info-hashes.
+type ScrapeResponse struct {
+	FailureReason string                `bencode:"failure reason,omitempty"`
+	Files         map[string]ScrapeFile `bencode:"files"`
+}
+
+// ScrapeFile holds the statistics of a torrent.
+type ScrapeFile struct {
+	Complete   int64  `bencode:"complete"`
+	Downloaded int64  `bencode:"downloaded"`
+	Incomplete int64  `bencode:"incomplete"`
+	Name       string `bencode:"name,omitempty"`
+}
+
+// File returns the statistics of the torrent with the given info-hash.
+func (r *ScrapeResponse) File(infoHash metainfo.HashV1) (ScrapeFile, bool) {
+	f, ok := r.Files[string(infoHash[:])]
+	return f, ok
+}
+
+// SetFile sets the statistics of the torrent with the given info-hash.
+func (r *ScrapeResponse) SetFile(infoHash metainfo.HashV1, f ScrapeFile) {
+	if r.Files == nil {
+		r.Files = make(map[string]ScrapeFile)
+	}
+	r.Files[string(infoHash[:])] = f
+}
+
+// String returns the address of the peer.
+func (p Peer) String() string {
+	return net.JoinHostPort(p.IP, strconv.Itoa(p.Port))
+}
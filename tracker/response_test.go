@@ -0,0 +1,113 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+func TestAnnounceResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  AnnounceResponse
+	}{
+		{
+			"Compact",
+			"d8:completei5e10:incompletei3e8:intervali1800e12:min intervali60e5:peers12:\x01\x02\x03\x04\x1a\xe1\x05\x06\x07\x08\x00\x50" +
+				"6:peers618:\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe110:tracker id3:abce",
+			AnnounceResponse{
+				Interval:    1800,
+				MinInterval: 60,
+				TrackerID:   "abc",
+				Complete:    5,
+				Incomplete:  3,
+				Peers:       Peers{{IP: "1.2.3.4", Port: 6881}, {IP: "5.6.7.8", Port: 80}},
+				Peers6:      Peers6{{IP: "2001:db8::1", Port: 6881}},
+			},
+		},
+		{
+			"Dictionary peers",
+			"d8:completei0e10:incompletei1e8:intervali900e5:peersld2:ip11:example.com7:peer id20:-XX0001-0123456789ab4:porti6881eed2:ip7:1.2.3.44:porti1eee15:warning message4:slowe",
+			AnnounceResponse{
+				WarningMessage: "slow",
+				Interval:       900,
+				Incomplete:     1,
+				Peers: Peers{
+					{ID: "-XX0001-0123456789ab", IP: "example.com", Port: 6881},
+					{IP: "1.2.3.4", Port: 1},
+				},
+			},
+		},
+		{
+			"Failure",
+			"d14:failure reason12:unregisterede",
+			AnnounceResponse{FailureReason: "unregistered"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got AnnounceResponse
+			if err := bencode.Unmarshal([]byte(test.input), &got); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("\ngot: %+v \nwant: %+v", got, test.want)
+			}
+
+			data, err := bencode.Marshal(got)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(data) != test.input {
+				t.Errorf("\ngot: %q \nwant: %q", data, test.input)
+			}
+		})
+	}
+}
+
+func TestPeersError(t *testing.T) {
+	tests := []string{
+		"d5:peers5:abcdee",
+		"d5:peersi1ee",
+		"d5:peersli1eee",
+		"d5:peersdee",
+		"d5:peersl1:xee",
+	}
+	for _, input := range tests {
+		var got AnnounceResponse
+		if err := bencode.Unmarshal([]byte(input), &got); err == nil {
+			t.Error("no error for", input)
+		}
+	}
+}
+
+func TestScrapeResponse(t *testing.T) {
+	var h metainfo.HashV1
+	for i := range h {
+		h[i] = byte(0xe0 + i%16)
+	}
+	input := "d5:filesd20:" + string(h[:]) + "d8:completei5e10:downloadedi50e10:incompletei10e4:name4:spameee"
+
+	var got ScrapeResponse
+	if err := bencode.Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	f, ok := got.File(h)
+	if want := (ScrapeFile{Complete: 5, Downloaded: 50, Incomplete: 10, Name: "spam"}); !ok || f != want {
+		t.Errorf("\ngot: %+v \nwant: %+v", f, want)
+	}
+
+	var resp ScrapeResponse
+	resp.SetFile(h, f)
+	data, err := bencode.Marshal(resp)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(data) != input {
+		t.Errorf("\ngot: %q \nwant: %q", data, input)
+	}
+}
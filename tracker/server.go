@@ -0,0 +1,246 @@
+package tracker
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ortymid/bencode"
+	"github.com/ortymid/bencode/metainfo"
+)
+
+// DefaultNumWant is the number of peers returned when a request does not
+// specify numwant.
+const DefaultNumWant = 50
+
+// Server is a minimal HTTP tracker which keeps its swarms in memory. It
+// serves announce requests on paths ending in "/announce" and scrape
+// requests on paths ending in "/scrape". It is meant for tests and does
+// not expire peers which stop announcing.
+type Server struct {
+	// Interval is the announce interval sent to peers, in seconds.
+	Interval int64
+
+	mu     sync.Mutex
+	swarms map[metainfo.HashV1]*swarm
+}
+
+type swarm struct {
+	peers      map[string]*swarmPeer // by peer id
+	order      []string              // peer ids in the order of the first announce
+	downloaded int64
+}
+
+type swarmPeer struct {
+	addr netip.AddrPort
+	left int64
+}
+
+// NewServer returns a server with no swarms.
+func NewServer() *Server {
+	return &Server{
+		Interval: 1800,
+		swarms:   make(map[metainfo.HashV1]*swarm),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var resp interface{}
+	switch path.Base(r.URL.Path) {
+	case "announce":
+		resp = s.announce(r)
+	case "scrape":
+		resp = s.scrape(r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	data, err := bencode.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(data)
+}
+
+// Peers returns the peers of the swarm with the given info-hash in the
+// order of their first announce.
+func (s *Server) Peers(infoHash metainfo.HashV1) []Peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sw, ok := s.swarms[infoHash]
+	if !ok {
+		return nil
+	}
+	peers := make([]Peer, len(sw.order))
+	for i, id := range sw.order {
+		peers[i] = sw.peers[id].peer(id)
+	}
+	return peers
+}
+
+func (p *swarmPeer) peer(id string) Peer {
+	return Peer{ID: id, IP: p.addr.Addr().String(), Port: int(p.addr.Port())}
+}
+
+func (s *Server) announce(r *http.Request) *AnnounceResponse {
+	q := r.URL.Query()
+	infoHash, ok := parseInfoHash(q.Get("info_hash"))
+	if !ok {
+		return &AnnounceResponse{FailureReason: "invalid info_hash"}
+	}
+	peerID := q.Get("peer_id")
+	if len(peerID) != 20 {
+		return &AnnounceResponse{FailureReason: "invalid peer_id"}
+	}
+	port, err := strconv.ParseUint(q.Get("port"), 10, 16)
+	if err != nil {
+		return &AnnounceResponse{FailureReason: "invalid port"}
+	}
+	left, err := strconv.ParseInt(q.Get("left"), 10, 64)
+	if err != nil {
+		return &AnnounceResponse{FailureReason: "invalid left"}
+	}
+	numWant := DefaultNumWant
+	if v := q.Get("numwant"); v != "" {
+		if numWant, err = strconv.Atoi(v); err != nil || numWant < 0 {
+			return &AnnounceResponse{FailureReason: "invalid numwant"}
+		}
+	}
+	ip, ok := peerIP(r)
+	if !ok {
+		return &AnnounceResponse{FailureReason: "invalid ip"}
+	}
+	addr := netip.AddrPortFrom(ip, uint16(port))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sw := s.swarms[infoHash]
+	if sw == nil {
+		sw = &swarm{peers: make(map[string]*swarmPeer)}
+		s.swarms[infoHash] = sw
+	}
+	sw.update(peerID, addr, left, q.Get("event"))
+
+	resp := &AnnounceResponse{Interval: s.Interval}
+	resp.Complete, resp.Incomplete = sw.counts()
+	compact := q.Get("compact") == "1"
+	noPeerID := q.Get("no_peer_id") == "1"
+	for _, id := range sw.order {
+		if id == peerID {
+			continue
+		}
+		if len(resp.Peers)+len(resp.Peers6) == numWant {
+			break
+		}
+		p := sw.peers[id].peer(id)
+		if compact || noPeerID {
+			p.ID = ""
+		}
+		if compact && !sw.peers[id].addr.Addr().Is4() {
+			resp.Peers6 = append(resp.Peers6, p)
+		} else {
+			resp.Peers = append(resp.Peers, p)
+		}
+	}
+	if resp.Peers == nil {
+		resp.Peers = Peers{}
+	}
+	return resp
+}
+
+// update applies an announce of a peer to the swarm.
+func (sw *swarm) update(id string, addr netip.AddrPort, left int64, event string) {
+	p, ok := sw.peers[id]
+	if event == EventStopped {
+		if ok {
+			delete(sw.peers, id)
+			for i := range sw.order {
+				if sw.order[i] == id {
+					sw.order = append(sw.order[:i], sw.order[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+	if !ok {
+		p = &swarmPeer{}
+		sw.peers[id] = p
+		sw.order = append(sw.order, id)
+	}
+	if event == EventCompleted && p.left != 0 {
+		sw.downloaded++
+	}
+	p.addr, p.left = addr, left
+}
+
+// counts returns the number of seeders and leechers.
+func (sw *swarm) counts() (complete, incomplete int64) {
+	for _, p := range sw.peers {
+		if p.left == 0 {
+			complete++
+		} else {
+			incomplete++
+		}
+	}
+	return complete, incomplete
+}
+
+func (s *Server) scrape(r *http.Request) *ScrapeResponse {
+	resp := &ScrapeResponse{Files: make(map[string]ScrapeFile)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hashes := r.URL.Query()["info_hash"]
+	if len(hashes) == 0 {
+		for h := range s.swarms {
+			hashes = append(hashes, string(h[:]))
+		}
+	}
+	for _, v := range hashes {
+		infoHash, ok := parseInfoHash(v)
+		if !ok {
+			return &ScrapeResponse{FailureReason: "invalid info_hash"}
+		}
+		sw, ok := s.swarms[infoHash]
+		if !ok {
+			continue
+		}
+		f := ScrapeFile{Downloaded: sw.downloaded}
+		f.Complete, f.Incomplete = sw.counts()
+		resp.SetFile(infoHash, f)
+	}
+	return resp
+}
+
+func parseInfoHash(s string) (h metainfo.HashV1, ok bool) {
+	if len(s) != len(h) {
+		return h, false
+	}
+	copy(h[:], s)
+	return h, true
+}
+
+// peerIP returns the ip parameter of the request if given, and the remote
+// address otherwise.
+func peerIP(r *http.Request) (netip.Addr, bool) {
+	host := r.URL.Query().Get("ip")
+	if host == "" {
+		var err error
+		if host, _, err = net.SplitHostPort(r.RemoteAddr); err != nil {
+			return netip.Addr{}, false
+		}
+	}
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
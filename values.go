@@ -1,11 +1,43 @@
 package bencode
 
-import "strconv"
+import (
+	"io"
+	"strconv"
+)
 
 // Value is a tree node.
 type Value interface {
 	Interface() interface{}
 	Bencode() []byte
+	// AppendBencode appends the bencoded value to dst and returns the
+	// extended buffer.
+	AppendBencode(dst []byte) []byte
+	// EncodedLen returns the length of the bencoded value.
+	EncodedLen() int
+	io.WriterTo
+}
+
+// writeValue writes the bencoded v to w in a single call.
+func writeValue(w io.Writer, v Value) (int64, error) {
+	b := v.AppendBencode(make([]byte, 0, v.EncodedLen()))
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// intLen returns the number of decimal digits in i, including the sign.
+func intLen(i int64) int {
+	n := 1
+	if i < 0 {
+		n++
+		if i == -1<<63 {
+			return 20
+		}
+		i = -i
+	}
+	for ; i >= 10; i /= 10 {
+		n++
+	}
+	return n
 }
 
 // Int is a representation of bencoded integer.
@@ -18,10 +50,24 @@ func (i Int) Interface() interface{} {
 
 // Bencode returns a bencoded integer.
 func (i Int) Bencode() []byte {
-	b := []byte{'i'}
-	b = strconv.AppendInt(b, int64(i), 10)
-	b = append(b, 'e')
-	return b
+	return i.AppendBencode(make([]byte, 0, i.EncodedLen()))
+}
+
+// AppendBencode appends a bencoded integer to dst.
+func (i Int) AppendBencode(dst []byte) []byte {
+	dst = append(dst, 'i')
+	dst = strconv.AppendInt(dst, int64(i), 10)
+	return append(dst, 'e')
+}
+
+// EncodedLen returns the length of a bencoded integer.
+func (i Int) EncodedLen() int {
+	return intLen(int64(i)) + 2
+}
+
+// WriteTo writes a bencoded integer to w.
+func (i Int) WriteTo(w io.Writer) (int64, error) {
+	return writeValue(w, i)
 }
 
 // String is a representation of bencoded string.
@@ -34,11 +80,33 @@ func (s String) Interface() interface{} {
 
 // Bencode returns a bencoded string.
 func (s String) Bencode() []byte {
-	var b []byte
-	b = strconv.AppendInt(b, int64(len(s)), 10)
+	return s.AppendBencode(make([]byte, 0, s.EncodedLen()))
+}
+
+// AppendBencode appends a bencoded string to dst.
+func (s String) AppendBencode(dst []byte) []byte {
+	dst = strconv.AppendInt(dst, int64(len(s)), 10)
+	dst = append(dst, ':')
+	return append(dst, s...)
+}
+
+// EncodedLen returns the length of a bencoded string.
+func (s String) EncodedLen() int {
+	return intLen(int64(len(s))) + 1 + len(s)
+}
+
+// WriteTo writes a bencoded string to w. The string is written without
+// copying it into an intermediate buffer.
+func (s String) WriteTo(w io.Writer) (int64, error) {
+	var prefix [21]byte
+	b := strconv.AppendInt(prefix[:0], int64(len(s)), 10)
 	b = append(b, ':')
-	b = append(b, s...)
-	return b
+	n, err := w.Write(b)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := io.WriteString(w, string(s))
+	return int64(n + m), err
 }
 
 // List is a representation of bencoded list as a slice of Value.
@@ -55,13 +123,30 @@ func (l List) Interface() interface{} {
 
 // Bencode returns a bencoded list.
 func (l List) Bencode() []byte {
-	b := []byte{'l'}
+	return l.AppendBencode(make([]byte, 0, l.EncodedLen()))
+}
+
+// AppendBencode appends a bencoded list to dst.
+func (l List) AppendBencode(dst []byte) []byte {
+	dst = append(dst, 'l')
 	for _, v := range l {
-		bval := v.Bencode()
-		b = append(b, bval...)
+		dst = v.AppendBencode(dst)
 	}
-	b = append(b, 'e')
-	return b
+	return append(dst, 'e')
+}
+
+// EncodedLen returns the length of a bencoded list.
+func (l List) EncodedLen() int {
+	n := 2
+	for _, v := range l {
+		n += v.EncodedLen()
+	}
+	return n
+}
+
+// WriteTo writes a bencoded list to w.
+func (l List) WriteTo(w io.Writer) (int64, error) {
+	return writeValue(w, l)
 }
 
 // Dict is a representation of bencoded dictionary. The need for preserving
@@ -73,6 +158,7 @@ func (l List) Bencode() []byte {
 type Dict struct {
 	keys []String
 	m    map[String]Value
+	raw  []byte
 }
 
 // DictItem is a helper struct which represents a dict key-value pair.
@@ -95,8 +181,16 @@ func (d *Dict) Get(key String) Value {
 	return d.m[key]
 }
 
+// Raw returns the exact bytes the dict was parsed from, or nil if the
+// parser did not capture them or the dict has been modified since. Changes
+// made to nested values are not tracked.
+func (d *Dict) Raw() []byte {
+	return d.raw
+}
+
 // Set puts a key-value pair into Dict.
 func (d *Dict) Set(key String, val Value) {
+	d.raw = nil
 	if d.m == nil {
 		d.m = make(map[String]Value)
 	}
@@ -113,6 +207,26 @@ func (d *Dict) Set(key String, val Value) {
 	d.m[key] = val
 }
 
+// Keys returns the keys of Dict in order.
+func (d *Dict) Keys() []String {
+	return append([]String(nil), d.keys...)
+}
+
+// Delete removes a key-value pair from Dict.
+func (d *Dict) Delete(key String) {
+	if _, ok := d.m[key]; !ok {
+		return
+	}
+	d.raw = nil
+	delete(d.m, key)
+	for i, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
 // Interface returns a map[string]interface{} representation of Dict put into interface{}.
 func (d *Dict) Interface() interface{} {
 	m := make(map[string]interface{}, len(d.m))
@@ -125,14 +239,29 @@ func (d *Dict) Interface() interface{} {
 // Bencode returns a bencoded dictionary. The order of key-value pairs
 // would be the same as with which Dict was created or updated.
 func (d *Dict) Bencode() []byte {
-	b := []byte{'d'}
+	return d.AppendBencode(make([]byte, 0, d.EncodedLen()))
+}
+
+// AppendBencode appends a bencoded dictionary to dst.
+func (d *Dict) AppendBencode(dst []byte) []byte {
+	dst = append(dst, 'd')
 	for _, key := range d.keys {
-		val := d.m[key]
-		bkey := key.Bencode()
-		bval := val.Bencode()
-		b = append(b, bkey...)
-		b = append(b, bval...)
+		dst = key.AppendBencode(dst)
+		dst = d.m[key].AppendBencode(dst)
 	}
-	b = append(b, 'e')
-	return b
+	return append(dst, 'e')
+}
+
+// EncodedLen returns the length of a bencoded dictionary.
+func (d *Dict) EncodedLen() int {
+	n := 2
+	for _, key := range d.keys {
+		n += key.EncodedLen() + d.m[key].EncodedLen()
+	}
+	return n
+}
+
+// WriteTo writes a bencoded dictionary to w.
+func (d *Dict) WriteTo(w io.Writer) (int64, error) {
+	return writeValue(w, d)
 }
@@ -1,6 +1,8 @@
 package bencode
 
 import (
+	"bytes"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -46,6 +48,32 @@ func TestDictGet(t *testing.T) {
 	}
 }
 
+func TestDictDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		key  String
+		want []String
+	}{
+		{"First", String("a"), []String{"b", "c"}},
+		{"Middle", String("b"), []String{"a", "c"}},
+		{"Not exist", String("notexist"), []String{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDict([]DictItem{{String("a"), Int(1)}, {String("b"), Int(2)}, {String("c"), Int(3)}}...)
+			d.Delete(test.key)
+
+			if got := d.Keys(); !reflect.DeepEqual(got, test.want) {
+				t.Error("got:", got, "want:", test.want)
+			}
+			if d.Get(test.key) != nil {
+				t.Error("got:", d.Get(test.key), "want:", nil)
+			}
+		})
+	}
+}
+
 func TestBencode(t *testing.T) {
 	tests := []struct {
 		name string
@@ -82,6 +110,43 @@ func TestBencode(t *testing.T) {
 	}
 }
 
+func TestAppendBencode(t *testing.T) {
+	tests := []struct {
+		name string
+		val  Value
+		want string
+	}{
+		{"Int/Max", Int(math.MaxInt64), `i9223372036854775807e`},
+		{"Int/Min", Int(math.MinInt64), `i-9223372036854775808e`},
+		{"String/Long", String("0123456789"), `10:0123456789`},
+		{"List/Empty", List{}, `le`},
+		{"Dict/Empty", NewDict(), `de`},
+		{"Dict/Nested", NewDict([]DictItem{{String("spam"), List{Int(-7), NewDict()}}}...), `d4:spamli-7edeee`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefix := []byte("prefix")
+			got := test.val.AppendBencode(prefix)
+			if string(got) != "prefix"+test.want {
+				t.Error("got:", string(got), "want:", "prefix"+test.want)
+			}
+			if n := test.val.EncodedLen(); n != len(test.want) {
+				t.Error("got length:", n, "want:", len(test.want))
+			}
+
+			var buf bytes.Buffer
+			n, err := test.val.WriteTo(&buf)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if buf.String() != test.want || n != int64(len(test.want)) {
+				t.Error("got:", buf.String(), n, "want:", test.want, len(test.want))
+			}
+		})
+	}
+}
+
 // func TestIntBencode(t *testing.T) {
 // 	tests := []struct {
 // 		name string